@@ -19,6 +19,8 @@ import {
   Tabs,
   Tab,
   Collapse,
+  Button,
+  Avatar,
 } from "@mui/material";
 import SendIcon from "@mui/icons-material/Send";
 import ChatIcon from "@mui/icons-material/Chat";
@@ -26,12 +28,22 @@ import CloseIcon from "@mui/icons-material/Close";
 import CardGiftcardIcon from "@mui/icons-material/CardGiftcard";
 import SentimentSatisfiedAltIcon from "@mui/icons-material/SentimentSatisfiedAlt";
 import InfoIcon from "@mui/icons-material/Info";
+import EditIcon from "@mui/icons-material/Edit";
+import DeleteIcon from "@mui/icons-material/Delete";
+import AttachFileIcon from "@mui/icons-material/AttachFile";
 import { useTranslation } from "react-i18next";
+import { useQueryClient } from "@tanstack/react-query";
 import { useForm } from "react-hook-form";
 import { zodResolver } from "@hookform/resolvers/zod";
 import { z } from "zod";
 import * as api from "../services/api";
-import { ChatMessage } from "../services/api";
+import {
+  ChatEvent,
+  ChatMessage,
+  ChatSide,
+  MESSAGE_EDIT_WINDOW_MS,
+} from "../services/api";
+import { ChatAttachmentView } from "./ChatAttachmentView";
 import { containsDangerousContent, MAX_MESSAGE_LENGTH } from "../utils/validator";
 
 const getMessageSchema = (t: (key: string, params?: any) => string) =>
@@ -51,6 +63,11 @@ const getMessageSchema = (t: (key: string, params?: any) => string) =>
 
 type MessageFormData = z.infer<ReturnType<typeof getMessageSchema>>;
 
+// Вложения чата (должны совпадать с MaxChatAttachmentSize и allowedChatAttachmentTypes на сервере)
+const MAX_ATTACHMENT_SIZE = 10 * 1024 * 1024;
+const ATTACHMENT_ACCEPT =
+  "image/jpeg,image/png,image/gif,image/webp,application/pdf,text/plain";
+
 interface AnonymousChatProps {
   raffleId: string;
   memberId: string; // ID текущего пользователя в этом розыгрыше
@@ -68,6 +85,11 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
   // Сообщения для обеих вкладок
   const [gifteeMessages, setGifteeMessages] = useState<ChatMessage[]>([]);
   const [santaMessages, setSantaMessages] = useState<ChatMessage[]>([]);
+  // Даритель, раскрытый после розыгрыша (null, пока он анонимен)
+  const [santa, setSanta] = useState<api.RevealedSanta | null>(null);
+  // Получатель сменил адрес, пока чат открыт
+  const [gifteeAddressChanged, setGifteeAddressChanged] = useState(false);
+  const queryClient = useQueryClient();
 
   const [loadingGiftee, setLoadingGiftee] = useState(true);
   const [loadingSanta, setLoadingSanta] = useState(true);
@@ -75,17 +97,38 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
   const [connected, setConnected] = useState(false);
   const [unreadGiftee, setUnreadGiftee] = useState(0);
   const [unreadSanta, setUnreadSanta] = useState(0);
+  const [typing, setTyping] = useState<Record<ChatSide, boolean>>({
+    giftee: false,
+    santa: false,
+  });
+  const [online, setOnline] = useState<Record<ChatSide, boolean>>({
+    giftee: false,
+    santa: false,
+  });
+  const [hasMore, setHasMore] = useState<Record<ChatSide, boolean>>({
+    giftee: false,
+    santa: false,
+  });
+  const [loadingEarlier, setLoadingEarlier] = useState(false);
+  const [editingId, setEditingId] = useState<string | null>(null);
+  const [editText, setEditText] = useState("");
+  const [uploading, setUploading] = useState(false);
 
   const wsRef = useRef<WebSocket | null>(null);
   const messagesEndRef = useRef<HTMLDivElement>(null);
+  const fileInputRef = useRef<HTMLInputElement>(null);
   const reconnectTimeoutRef = useRef<NodeJS.Timeout | undefined>(undefined);
+  const disposedRef = useRef(false); // Компонент размонтирован - не переподключаемся
   const lastMessageIdRef = useRef<string | null>(null);
+  const typingSentAtRef = useRef<number>(0);
+  const typingTimeoutsRef = useRef<Partial<Record<ChatSide, NodeJS.Timeout>>>({});
 
   // React Hook Form с zod валидацией
   const {
     register,
     handleSubmit,
     reset,
+    getValues,
     formState: { errors: formErrors },
   } = useForm<MessageFormData>({
     resolver: zodResolver(getMessageSchema(t)),
@@ -97,6 +140,7 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
   // Текущие сообщения
   const currentMessages = activeTab === 0 ? gifteeMessages : santaMessages;
   const currentLoading = activeTab === 0 ? loadingGiftee : loadingSanta;
+  const currentSide: ChatSide = activeTab === 0 ? "giftee" : "santa";
 
   // Определяем, является ли сообщение моим
   const isMyMessage = useCallback(
@@ -129,8 +173,9 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
     // Загружаем сообщения с получателем (я - даритель)
     try {
       setLoadingGiftee(true);
-      const messages = await api.getChatWithGiftee(raffleId);
-      setGifteeMessages(messages);
+      const page = await api.getChatWithGiftee(raffleId);
+      setGifteeMessages(page.messages);
+      setHasMore((prev) => ({ ...prev, giftee: page.has_more }));
     } catch (err: any) {
       console.error("Failed to load giftee messages:", err);
       if (err.response?.status !== 404) {
@@ -143,8 +188,10 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
     // Загружаем сообщения с дарителем (я - получатель)
     try {
       setLoadingSanta(true);
-      const messages = await api.getChatWithSanta(raffleId);
-      setSantaMessages(messages);
+      const page = await api.getChatWithSanta(raffleId);
+      setSantaMessages(page.messages);
+      setSanta(page.santa);
+      setHasMore((prev) => ({ ...prev, santa: page.has_more }));
     } catch (err: any) {
       console.error("Failed to load santa messages:", err);
       if (err.response?.status !== 404) {
@@ -155,13 +202,49 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
     }
   }, [raffleId]);
 
+  // Подгружаем более ранние сообщения текущей вкладки
+  const loadEarlier = async () => {
+    const side = currentSide;
+    const oldest = currentMessages[0];
+    if (!oldest || loadingEarlier) {
+      return;
+    }
+    setLoadingEarlier(true);
+    try {
+      const page =
+        side === "giftee"
+          ? await api.getChatWithGiftee(raffleId, { before: oldest.id })
+          : await api.getChatWithSanta(raffleId, { before: oldest.id });
+      const prepend = (prev: ChatMessage[]) => [
+        ...page.messages.filter((m) => !prev.some((p) => p.id === m.id)),
+        ...prev,
+      ];
+      if (side === "giftee") {
+        setGifteeMessages(prepend);
+      } else {
+        setSantaMessages(prepend);
+      }
+      setHasMore((prev) => ({ ...prev, [side]: page.has_more }));
+    } catch (err: any) {
+      setError(err.response?.data?.error || "Failed to load messages");
+    } finally {
+      setLoadingEarlier(false);
+    }
+  };
+
   // Подключение к WebSocket
-  const connectWebSocket = useCallback(() => {
-    const token = localStorage.getItem("token");
-    if (!token) {
-      setError("Not authenticated");
+  const connectWebSocket = useCallback(async () => {
+    // Вместо JWT в URL - одноразовый билет: адрес сокета попадает в логи прокси
+    let ticket: string;
+    try {
+      ({ ticket } = await api.getChatTicket(raffleId));
+    } catch (err: any) {
+      if (disposedRef.current) return;
+      setError(err.response?.data?.error || "Connection error");
+      reconnectTimeoutRef.current = setTimeout(() => connectWebSocket(), 3000);
       return;
     }
+    if (disposedRef.current) return;
 
     const protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
     const apiUrl = import.meta.env.VITE_API_URL || "";
@@ -179,8 +262,8 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
       wsBaseUrl = window.location.host + "/api";
     }
 
-    const wsUrl = `${protocol}//${wsBaseUrl}/raffles/${raffleId}/chat/ws?token=${encodeURIComponent(
-      token
+    const wsUrl = `${protocol}//${wsBaseUrl}/raffles/${raffleId}/chat/ws?ticket=${encodeURIComponent(
+      ticket
     )}`;
 
     const ws = new WebSocket(wsUrl);
@@ -191,45 +274,122 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
       setError(null);
     };
 
-    ws.onmessage = (event) => {
-      try {
-        const message: ChatMessage = JSON.parse(event.data);
-
-        // Проверяем, не дубликат ли это сообщение
-        if (message.id === lastMessageIdRef.current) {
+    // Обработка одного события сервера
+    const handleEvent = (chatEvent: ChatEvent) => {
+      switch (chatEvent.type) {
+        case "typing": {
+          const side = chatEvent.chat;
+          setTyping((prev) => ({ ...prev, [side]: chatEvent.typing }));
+          // Индикатор гаснет сам, если собеседник пропал, не отправив typing=false
+          clearTimeout(typingTimeoutsRef.current[side]);
+          if (chatEvent.typing) {
+            typingTimeoutsRef.current[side] = setTimeout(
+              () => setTyping((prev) => ({ ...prev, [side]: false })),
+              6000
+            );
+          }
           return;
         }
-
-        lastMessageIdRef.current = message.id;
-
-        // Определяем, к какой вкладке относится сообщение на основе santa_id и giftee_id
-        // Если я Santa (мой member_id === santa_id), то переписка идёт в gifteeMessages
-        // Если я Giftee (мой member_id === giftee_id), то переписка идёт в santaMessages
-
-        if (message.santa_id === memberId) {
-          // Я Санта в этом чате -> сообщения идут в gifteeMessages (вкладка "Chat with your Giftee")
-          setGifteeMessages((prev) => {
-            if (prev.some((m) => m.id === message.id)) return prev;
-            return [...prev, message];
-          });
-        } else if (message.giftee_id === memberId) {
-          // Я Получатель в этом чате -> сообщения идут в santaMessages (вкладка "Chat with your Santa")
-          setSantaMessages((prev) => {
-            if (prev.some((m) => m.id === message.id)) return prev;
-            return [...prev, message];
-          });
+        case "presence":
+          setOnline((prev) => ({ ...prev, [chatEvent.chat]: chatEvent.online }));
+          if (!chatEvent.online) {
+            setTyping((prev) => ({ ...prev, [chatEvent.chat]: false }));
+          }
+          return;
+        case "error":
+          setError(chatEvent.error);
+          return;
+        case "read": {
+          // Квитанция о прочтении (или синхронизация с другой вкладкой)
+          const ids = new Set(chatEvent.message_ids);
+          const markRead = (prev: ChatMessage[]) =>
+            prev.map((m) =>
+              ids.has(m.id) && !m.read_at ? { ...m, read_at: chatEvent.read_at } : m
+            );
+          if (chatEvent.chat === "giftee") {
+            setGifteeMessages(markRead);
+          } else {
+            setSantaMessages(markRead);
+          }
+          return;
         }
+        case "unread":
+          // Счетчики считает сервер - они одинаковы во всех вкладках
+          setUnreadGiftee(chatEvent.unread_from_giftee);
+          setUnreadSanta(chatEvent.unread_from_santa);
+          return;
+        case "giftee_address_changed":
+          // Отметка сохраняется на сервере - страница получателя покажет ее после обновления
+          setGifteeAddressChanged(true);
+          queryClient.invalidateQueries({ queryKey: ["giftee", raffleId] });
+          return;
+        case "message":
+          handleMessage(chatEvent);
+          return;
+        case "edit":
+        case "delete":
+          replaceMessage(chatEvent);
+          return;
+        default:
+          // ack и прочие уведомления чату не нужны
+          return;
+      }
+    };
 
-        // Если чат закрыт, увеличиваем счетчик непрочитанных
-        if (!open) {
-          if (message.santa_id === memberId) {
-            setUnreadGiftee((prev) => prev + 1);
-          } else if (message.giftee_id === memberId) {
-            setUnreadSanta((prev) => prev + 1);
-          }
+    const handleMessage = (message: ChatMessage) => {
+      // Проверяем, не дубликат ли это сообщение
+      if (message.id === lastMessageIdRef.current) {
+        return;
+      }
+
+      lastMessageIdRef.current = message.id;
+
+      // Определяем, к какой вкладке относится сообщение на основе santa_id и giftee_id
+      // Если я Santa (мой member_id === santa_id), то переписка идёт в gifteeMessages
+      // Если я Giftee (мой member_id === giftee_id), то переписка идёт в santaMessages
+
+      if (message.santa_id === memberId) {
+        // Я Санта в этом чате -> сообщения идут в gifteeMessages (вкладка "Chat with your Giftee")
+        setGifteeMessages((prev) => {
+          if (prev.some((m) => m.id === message.id)) return prev;
+          return [...prev, message];
+        });
+      } else if (message.giftee_id === memberId) {
+        // Я Получатель в этом чате -> сообщения идут в santaMessages (вкладка "Chat with your Santa")
+        setSantaMessages((prev) => {
+          if (prev.some((m) => m.id === message.id)) return prev;
+          return [...prev, message];
+        });
+      }
+
+
+      // Сообщение пришло - собеседник больше не печатает
+      const side: ChatSide = message.santa_id === memberId ? "giftee" : "santa";
+      if (!isMyMessage(message)) {
+        setTyping((prev) => ({ ...prev, [side]: false }));
+      }
+    };
+
+    // Правка или удаление: заменяем сообщение на актуальное состояние
+    const replaceMessage = (message: ChatMessage) => {
+      const replace = (prev: ChatMessage[]) =>
+        prev.map((m) => (m.id === message.id ? { ...m, ...message } : m));
+      if (message.santa_id === memberId) {
+        setGifteeMessages(replace);
+      } else {
+        setSantaMessages(replace);
+      }
+    };
+
+    ws.onmessage = (event) => {
+      // Сервер может отправить несколько событий в одном кадре, по одному на строку
+      for (const line of String(event.data).split("\n")) {
+        if (!line.trim()) continue;
+        try {
+          handleEvent(JSON.parse(line));
+        } catch (err) {
+          console.error("Failed to parse message:", err);
         }
-      } catch (err) {
-        console.error("Failed to parse message:", err);
       }
     };
 
@@ -241,8 +401,10 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
     ws.onclose = () => {
       console.log("WebSocket disconnected");
       setConnected(false);
+      // Сокет закрыт нами (размонтирование или переподключение с новыми параметрами)
+      if (disposedRef.current || wsRef.current !== ws) return;
 
-      // Переподключение через 3 секунды
+      // Переподключение через 3 секунды (с новым билетом)
       reconnectTimeoutRef.current = setTimeout(() => {
         console.log("Reconnecting...");
         connectWebSocket();
@@ -250,16 +412,19 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
     };
 
     wsRef.current = ws;
-  }, [raffleId, open]);
+  }, [raffleId, open, memberId, isMyMessage, queryClient]);
 
   // Инициализация
   useEffect(() => {
+    disposedRef.current = false;
     loadMessages();
     connectWebSocket();
 
     return () => {
+      disposedRef.current = true;
       if (wsRef.current) {
         wsRef.current.close();
+        wsRef.current = null;
       }
       if (reconnectTimeoutRef.current) {
         clearTimeout(reconnectTimeoutRef.current);
@@ -267,6 +432,83 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
     };
   }, [loadMessages, connectWebSocket]);
 
+  // Свое сообщение можно править и удалять только вскоре после отправки
+  const canChange = (message: ChatMessage) =>
+    isMyMessage(message) &&
+    !message.deleted_at &&
+    Date.now() - new Date(message.created_at).getTime() < MESSAGE_EDIT_WINDOW_MS;
+
+  // Изменения приходят и через WebSocket, но применяем ответ сразу - сокет может быть отключен
+  const applyChange = (side: ChatSide, message: ChatMessage) => {
+    const replace = (prev: ChatMessage[]) =>
+      prev.map((m) => (m.id === message.id ? { ...m, ...message } : m));
+    if (side === "giftee") {
+      setGifteeMessages(replace);
+    } else {
+      setSantaMessages(replace);
+    }
+  };
+
+  const handleSaveEdit = async (message: ChatMessage) => {
+    const content = editText.trim();
+    if (!content || content === message.content) {
+      setEditingId(null);
+      return;
+    }
+    try {
+      const updated = await api.editChatMessage(raffleId, message.id, content);
+      applyChange(currentSide, updated);
+      setEditingId(null);
+    } catch (err: any) {
+      setError(err.response?.data?.error || "Failed to edit message");
+    }
+  };
+
+  const handleDelete = async (message: ChatMessage) => {
+    try {
+      await api.deleteChatMessage(raffleId, message.id);
+      applyChange(currentSide, {
+        ...message,
+        content: "",
+        deleted_at: new Date().toISOString(),
+      });
+    } catch (err: any) {
+      setError(err.response?.data?.error || "Failed to delete message");
+    }
+  };
+
+  // Отправка вложения: текст из поля ввода уходит подписью.
+  // Сообщение придет и через WebSocket, но добавляем его сразу (дубликаты отсекаются по id)
+  const handleAttach = async (file: File) => {
+    if (file.size > MAX_ATTACHMENT_SIZE) {
+      setError(t("chat.file_too_large", "File is too large (max 10 MB)"));
+      return;
+    }
+    const role = activeTab === 0 ? "santa" : "giftee";
+    setUploading(true);
+    try {
+      const message = await api.sendChatAttachment(
+        raffleId,
+        role,
+        file,
+        getValues("message").trim()
+      );
+      const add = (prev: ChatMessage[]) =>
+        prev.some((m) => m.id === message.id) ? prev : [...prev, message];
+      if (currentSide === "giftee") {
+        setGifteeMessages(add);
+      } else {
+        setSantaMessages(add);
+      }
+      sendTyping(false);
+      reset();
+    } catch (err: any) {
+      setError(err.response?.data?.error || "Failed to send attachment");
+    } finally {
+      setUploading(false);
+    }
+  };
+
   // Отправка сообщения
   const handleSend = (data: MessageFormData) => {
     if (
@@ -282,19 +524,58 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
 
     wsRef.current.send(
       JSON.stringify({
+        type: "message",
         content: data.message.trim(),
         role: role,
       })
     );
+    sendTyping(false);
 
     reset();
   };
 
+  // Отмечаем прочитанными входящие сообщения открытой вкладки
+  useEffect(() => {
+    if (!open || !connected || !wsRef.current) {
+      return;
+    }
+    const unreadIds = currentMessages
+      .filter((m) => !isMyMessage(m) && !m.read_at)
+      .map((m) => m.id);
+    if (unreadIds.length === 0) {
+      return;
+    }
+    wsRef.current.send(
+      JSON.stringify({
+        type: "read",
+        role: activeTab === 0 ? "santa" : "giftee",
+        message_ids: unreadIds,
+      })
+    );
+  }, [open, connected, activeTab, currentMessages, isMyMessage]);
+
+  // Индикатор набора: не чаще раза в 3 секунды, пока пользователь печатает
+  const sendTyping = (isTyping: boolean) => {
+    if (!wsRef.current || wsRef.current.readyState !== WebSocket.OPEN) {
+      return;
+    }
+    const now = Date.now();
+    if (isTyping && now - typingSentAtRef.current < 3000) {
+      return;
+    }
+    typingSentAtRef.current = isTyping ? now : 0;
+    wsRef.current.send(
+      JSON.stringify({
+        type: "typing",
+        role: activeTab === 0 ? "santa" : "giftee",
+        typing: isTyping,
+      })
+    );
+  };
+
   // Открытие/закрытие чата
   const handleOpen = () => {
     setOpen(true);
-    setUnreadGiftee(0);
-    setUnreadSanta(0);
   };
 
   const handleClose = () => {
@@ -303,12 +584,6 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
 
   const handleTabChange = (_event: React.SyntheticEvent, newValue: number) => {
     setActiveTab(newValue);
-    // Сбрасываем счетчик для активной вкладки
-    if (newValue === 0) {
-      setUnreadGiftee(0);
-    } else {
-      setUnreadSanta(0);
-    }
     // Прокручиваем вниз после переключения вкладки
     setTimeout(() => scrollToBottom(true), 0);
   };
@@ -457,6 +732,42 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
         />
       </Tabs>
 
+      {/* Получатель сменил адрес */}
+      <Collapse in={activeTab === 0 && gifteeAddressChanged}>
+        <Alert
+          severity="warning"
+          onClose={() => setGifteeAddressChanged(false)}
+          sx={{ borderRadius: 0 }}
+        >
+          {t(
+            "chat.giftee_address_changed",
+            "Your giftee has updated their delivery address. Check it before sending the gift."
+          )}
+        </Alert>
+      </Collapse>
+
+      {/* Раскрытый даритель */}
+      {activeTab === 1 && santa && (
+        <Box
+          sx={{
+            display: "flex",
+            alignItems: "center",
+            gap: 1,
+            px: 2,
+            py: 1,
+            borderBottom: 1,
+            borderColor: "divider",
+          }}
+        >
+          <Avatar src={santa.avatar_url || undefined} sx={{ width: 32, height: 32 }}>
+            {santa.name[0]}
+          </Avatar>
+          <Typography variant="body2">
+            {t("chat.santa_revealed", "Your Santa: {{name}}", { name: santa.name })}
+          </Typography>
+        </Box>
+      )}
+
       {/* Сообщения */}
       <Box
         sx={{
@@ -512,6 +823,17 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
           </Box>
         ) : (
           <Stack spacing={1}>
+            {hasMore[currentSide] && (
+              <Box display="flex" justifyContent="center">
+                <Button
+                  size="small"
+                  onClick={loadEarlier}
+                  disabled={loadingEarlier}
+                >
+                  {t("chat.load_earlier", "Load earlier messages")}
+                </Button>
+              </Box>
+            )}
             {currentMessages.map((message) => {
               const isMine = isMyMessage(message);
               return (
@@ -531,12 +853,54 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
                       color: isMine ? "primary.contrastText" : "text.primary",
                     }}
                   >
-                    <Typography
-                      variant="body1"
-                      sx={{ whiteSpace: "pre-wrap", wordBreak: "break-word" }}
-                    >
-                      {message.content}
-                    </Typography>
+                    {editingId === message.id ? (
+                      <Stack spacing={1}>
+                        <TextField
+                          value={editText}
+                          onChange={(e) => setEditText(e.target.value)}
+                          size="small"
+                          multiline
+                          autoFocus
+                          inputProps={{ maxLength: MAX_MESSAGE_LENGTH }}
+                          sx={{ bgcolor: "background.paper", borderRadius: 1 }}
+                        />
+                        <Stack direction="row" spacing={1} justifyContent="flex-end">
+                          <Button
+                            size="small"
+                            color="inherit"
+                            onClick={() => setEditingId(null)}
+                          >
+                            {t("common.cancel", "Cancel")}
+                          </Button>
+                          <Button
+                            size="small"
+                            variant="contained"
+                            color="secondary"
+                            onClick={() => handleSaveEdit(message)}
+                          >
+                            {t("common.save", "Save")}
+                          </Button>
+                        </Stack>
+                      </Stack>
+                    ) : message.deleted_at ? (
+                      <Typography variant="body2" sx={{ fontStyle: "italic", opacity: 0.7 }}>
+                        {t("chat.message_deleted", "Message deleted")}
+                      </Typography>
+                    ) : (
+                      <>
+                        {message.attachment && (
+                          <ChatAttachmentView raffleId={raffleId} message={message} />
+                        )}
+                        {message.content && (
+                          <Typography
+                            variant="body1"
+                            sx={{ whiteSpace: "pre-wrap", wordBreak: "break-word" }}
+                          >
+                            {message.content}
+                          </Typography>
+                        )}
+                      </>
+                    )}
                     <Typography
                       variant="caption"
                       sx={{
@@ -546,11 +910,36 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
                         textAlign: "right",
                       }}
                     >
+                      {message.edited_at && !message.deleted_at &&
+                        `${t("chat.edited", "edited")} · `}
                       {new Date(message.created_at).toLocaleTimeString([], {
                         hour: "2-digit",
                         minute: "2-digit",
                       })}
                     </Typography>
+                    {canChange(message) && editingId !== message.id && (
+                      <Stack direction="row" justifyContent="flex-end">
+                        <IconButton
+                          size="small"
+                          color="inherit"
+                          aria-label={t("chat.edit", "Edit")}
+                          onClick={() => {
+                            setEditingId(message.id);
+                            setEditText(message.content);
+                          }}
+                        >
+                          <EditIcon fontSize="inherit" />
+                        </IconButton>
+                        <IconButton
+                          size="small"
+                          color="inherit"
+                          aria-label={t("chat.delete", "Delete")}
+                          onClick={() => handleDelete(message)}
+                        >
+                          <DeleteIcon fontSize="inherit" />
+                        </IconButton>
+                      </Stack>
+                    )}
                   </Paper>
                 </Box>
               );
@@ -560,6 +949,19 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
         )}
       </Box>
 
+      {/* Статус собеседника */}
+      <Typography
+        variant="caption"
+        color="text.secondary"
+        sx={{ px: 2, pt: 0.5, minHeight: 20 }}
+      >
+        {typing[currentSide]
+          ? t("chat.typing", "Typing...")
+          : online[currentSide]
+          ? t("chat.online", "Online")
+          : ""}
+      </Typography>
+
       {/* Поле ввода */}
       <Box
         component="form"
@@ -573,8 +975,29 @@ export const AnonymousChat = ({ raffleId, memberId }: AnonymousChatProps) => {
           bgcolor: "background.paper",
         }}
       >
+        <input
+          ref={fileInputRef}
+          type="file"
+          hidden
+          accept={ATTACHMENT_ACCEPT}
+          onChange={(e) => {
+            const file = e.target.files?.[0];
+            e.target.value = "";
+            if (file) handleAttach(file);
+          }}
+        />
+        <IconButton
+          disabled={!connected || uploading}
+          onClick={() => fileInputRef.current?.click()}
+          aria-label={t("chat.attach", "Attach file")}
+          sx={{ alignSelf: "flex-end" }}
+        >
+          {uploading ? <CircularProgress size={24} /> : <AttachFileIcon />}
+        </IconButton>
         <TextField
-          {...register("message")}
+          {...register("message", {
+            onChange: (e) => sendTyping(e.target.value.length > 0),
+          })}
           fullWidth
           multiline
           maxRows={3}
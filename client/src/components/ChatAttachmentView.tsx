@@ -0,0 +1,92 @@
+import { useEffect, useState } from "react";
+import { Box, Button, CircularProgress } from "@mui/material";
+import AttachFileIcon from "@mui/icons-material/AttachFile";
+import * as api from "../services/api";
+import { ChatMessage } from "../services/api";
+
+interface ChatAttachmentViewProps {
+  raffleId: string;
+  message: ChatMessage;
+}
+
+const isImage = (contentType: string) =>
+  ["image/jpeg", "image/png", "image/gif", "image/webp"].includes(contentType);
+
+const formatSize = (bytes: number) =>
+  bytes < 1024 * 1024
+    ? `${Math.max(1, Math.round(bytes / 1024))} KB`
+    : `${(bytes / 1024 / 1024).toFixed(1)} MB`;
+
+// Вложение сообщения чата. Файлы лежат в закрытом хранилище:
+// ссылку (живет несколько минут) запрашиваем у API при показе картинки или по клику на файл.
+export const ChatAttachmentView = ({ raffleId, message }: ChatAttachmentViewProps) => {
+  const attachment = message.attachment!;
+  const image = isImage(attachment.content_type);
+  const [imageUrl, setImageUrl] = useState<string | null>(null);
+  const [opening, setOpening] = useState(false);
+
+  useEffect(() => {
+    if (!image) return;
+    let cancelled = false;
+    api
+      .getChatAttachmentUrl(raffleId, message.id)
+      .then(({ url }) => !cancelled && setImageUrl(url))
+      .catch((err) => console.error("Failed to load attachment:", err));
+    return () => {
+      cancelled = true;
+    };
+  }, [raffleId, message.id, image]);
+
+  // Ссылка живет несколько минут - открываем файл по свежей.
+  // Окно открываем сразу по клику, иначе после запроса его заблокирует браузер
+  const handleOpen = async () => {
+    const win = window.open("", "_blank");
+    if (win) win.opener = null;
+    setOpening(true);
+    try {
+      const { url } = await api.getChatAttachmentUrl(raffleId, message.id);
+      if (win) {
+        win.location.href = url;
+      } else {
+        window.location.href = url;
+      }
+    } catch (err) {
+      win?.close();
+      console.error("Failed to open attachment:", err);
+    } finally {
+      setOpening(false);
+    }
+  };
+
+  if (image) {
+    return (
+      <Box
+        sx={{ mb: message.content ? 1 : 0, cursor: "pointer", minHeight: 40 }}
+        onClick={handleOpen}
+      >
+        {imageUrl ? (
+          <Box
+            component="img"
+            src={imageUrl}
+            alt={attachment.name}
+            sx={{ display: "block", maxWidth: "100%", maxHeight: 240, borderRadius: 1 }}
+          />
+        ) : (
+          <CircularProgress size={20} color="inherit" />
+        )}
+      </Box>
+    );
+  }
+
+  return (
+    <Button
+      size="small"
+      color="inherit"
+      startIcon={opening ? <CircularProgress size={14} color="inherit" /> : <AttachFileIcon />}
+      onClick={handleOpen}
+      sx={{ mb: message.content ? 1 : 0, textTransform: "none", textAlign: "left" }}
+    >
+      {attachment.name} ({formatSize(attachment.size)})
+    </Button>
+  );
+};
@@ -211,6 +211,13 @@ const GifteePage = () => {
           <Alert severity="info" sx={{ mb: { xs: 2, sm: 3 } }}>
             {t("giftee.privacyNotice")}
           </Alert>
+          {giftee.address_changed_at && (
+            <Alert severity="warning" sx={{ mb: { xs: 2, sm: 3 } }}>
+              {t("giftee.addressChanged", {
+                date: new Date(giftee.address_changed_at).toLocaleString(),
+              })}
+            </Alert>
+          )}
 
           {/* Телефон */}
           {giftee.phone && (
@@ -201,27 +201,165 @@ export interface ChatMessage {
   from_santa: boolean;
   content: string;
   read_at: string | null;
+  edited_at?: string | null;
+  deleted_at?: string | null; // У удаленного сообщения content пустой
   created_at: string;
+  // Фото или файл; content тогда - необязательная подпись
+  attachment?: { name: string; content_type: string; size: number } | null;
+}
+
+// Сколько времени после отправки свое сообщение можно править и удалять (MessageEditWindow на сервере)
+export const MESSAGE_EDIT_WINDOW_MS = 15 * 60 * 1000;
+
+// События WebSocket-чата. chat - в каком из двух чатов пары ("giftee" - с моим получателем, "santa" - с моим дарителем)
+export type ChatSide = "giftee" | "santa";
+
+export type ChatEvent =
+  | ({ type: "message" | "edit" | "delete" } & ChatMessage)
+  | { type: "typing"; chat: ChatSide; typing: boolean }
+  | { type: "presence"; chat: ChatSide; online: boolean }
+  | { type: "read"; chat: ChatSide; message_ids: string[]; read_at: string }
+  | {
+      type: "unread";
+      unread_from_giftee: number;
+      unread_from_santa: number;
+      total: number;
+    }
+  | { type: "giftee_address_changed"; fields: string[]; changed_at: string }
+  | { type: "ack"; client_id?: string; id: string; created_at: string }
+  | { type: "error"; client_id?: string; error: string };
+
+// Курсоры истории чата: before - более ранние сообщения, after - более поздние (ID сообщения)
+export interface ChatPageParams {
+  before?: string;
+  after?: string;
+  limit?: number;
+}
+
+export interface ChatPage {
+  messages: ChatMessage[];
+  has_more: boolean;
 }
 
 export const getChatWithGiftee = async (
-  raffleId: string
-): Promise<ChatMessage[]> => {
-  const { data } = await api.get<ChatMessage[]>(
-    `/raffles/${raffleId}/chat/giftee`
+  raffleId: string,
+  params?: ChatPageParams
+): Promise<ChatPage> => {
+  const { data } = await api.get<ChatPage>(
+    `/raffles/${raffleId}/chat/giftee`,
+    { params }
   );
   return data;
 };
 
+export interface RevealedSanta {
+  name: string;
+  avatar_url: string | null;
+}
+
+export interface ChatWithSanta extends ChatPage {
+  santa: RevealedSanta | null;
+}
+
 export const getChatWithSanta = async (
-  raffleId: string
-): Promise<ChatMessage[]> => {
-  const { data } = await api.get<ChatMessage[]>(
-    `/raffles/${raffleId}/chat/santa`
+  raffleId: string,
+  params?: ChatPageParams
+): Promise<ChatWithSanta> => {
+  const { data } = await api.get<ChatWithSanta>(
+    `/raffles/${raffleId}/chat/santa`,
+    { params }
+  );
+  return data;
+};
+
+// Отметить прочитанными сообщения собеседника (без messageIds - все)
+export const markChatRead = async (
+  raffleId: string,
+  chat: ChatSide,
+  messageIds?: string[]
+): Promise<{ marked: number }> => {
+  const { data } = await api.post(
+    `/raffles/${raffleId}/chat/${chat}/read`,
+    messageIds ? { message_ids: messageIds } : undefined
+  );
+  return data;
+};
+
+export const editChatMessage = async (
+  raffleId: string,
+  messageId: string,
+  content: string
+): Promise<ChatMessage> => {
+  const { data } = await api.put(
+    `/raffles/${raffleId}/chat/messages/${messageId}`,
+    { content }
   );
   return data;
 };
 
+export const deleteChatMessage = async (
+  raffleId: string,
+  messageId: string
+): Promise<void> => {
+  await api.delete(`/raffles/${raffleId}/chat/messages/${messageId}`);
+};
+
+// Предыдущие версии своего сообщения
+export const getChatMessageHistory = async (
+  raffleId: string,
+  messageId: string
+): Promise<{ content: string; replaced_at: string }[]> => {
+  const { data } = await api.get(
+    `/raffles/${raffleId}/chat/messages/${messageId}/history`
+  );
+  return data;
+};
+
+// Отправить в чат фото или файл (role: "santa" - пишу получателю, "giftee" - дарителю)
+export const sendChatAttachment = async (
+  raffleId: string,
+  role: "santa" | "giftee",
+  file: File,
+  caption?: string
+): Promise<ChatMessage> => {
+  const formData = new FormData();
+  formData.append("file", file);
+  formData.append("role", role);
+  if (caption) {
+    formData.append("content", caption);
+  }
+
+  const { data } = await api.post<ChatMessage>(
+    `/raffles/${raffleId}/chat/attachments`,
+    formData,
+    {
+      headers: {
+        "Content-Type": "multipart/form-data",
+      },
+    }
+  );
+  return data;
+};
+
+// Временная ссылка на вложение (доступна только участникам пары)
+export const getChatAttachmentUrl = async (
+  raffleId: string,
+  messageId: string
+): Promise<{ url: string; expires_at: string }> => {
+  const { data } = await api.get(
+    `/raffles/${raffleId}/chat/messages/${messageId}/attachment`
+  );
+  return data;
+};
+
+// Одноразовый билет на подключение к WebSocket чата (живет ~30 секунд)
+export const getChatTicket = async (
+  raffleId: string
+): Promise<{ ticket: string; expires_at: string }> => {
+  const { data } = await api.post(`/raffles/${raffleId}/chat/ticket`);
+  return data;
+};
+
 export const getUnreadCount = async (
   raffleId: string
 ): Promise<{
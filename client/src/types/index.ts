@@ -112,6 +112,10 @@ export interface Giftee {
   
   wishlist?: string | null;
   anti_wishlist?: string | null;
+
+  // Последняя смена адреса после жеребьевки
+  address_changed_at?: string | null;
+  address_changed_fields?: string[] | null;
 }
 
 // Информация об участнике в исключении
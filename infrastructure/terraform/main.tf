@@ -427,6 +427,16 @@ resource "aws_s3_bucket_policy" "frontend" {
         Principal = "*"
         Action    = "s3:GetObject"
         Resource  = "${aws_s3_bucket.frontend.arn}/avatars/*"
+      },
+      {
+        # Вложения чата отдаются только по presigned URL от API, не через сайт
+        Sid       = "DenyCloudFrontPrivate"
+        Effect    = "Deny"
+        Principal = {
+          Service = "cloudfront.amazonaws.com"
+        }
+        Action   = "s3:GetObject"
+        Resource = "${aws_s3_bucket.frontend.arn}/private/*"
       }
     ]
   })
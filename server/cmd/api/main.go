@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
 	"secret-santa/internal/config"
+	"secret-santa/internal/currency"
 	"secret-santa/internal/database"
 	"secret-santa/internal/handlers"
 	"secret-santa/internal/middleware"
+	"secret-santa/internal/pubsub"
 	"secret-santa/internal/storage"
 
 	"github.com/gin-contrib/cors"
@@ -44,9 +48,20 @@ func main() {
 		log.Fatal("Failed to initialize S3 storage:", err)
 	}
 
+	// Pub/sub для событий чата: с LISTEN/NOTIFY экземпляры API видят клиентов друг друга
+	var ps pubsub.PubSub
+	if cfg.HubPubSub == "memory" {
+		ps = pubsub.NewMemory()
+	} else {
+		ps = pubsub.NewPostgres(database.DSN(cfg))
+	}
+	defer ps.Close()
+
 	// Initialize WebSocket Hub с ключом шифрования
-	hub := handlers.NewHub(db, cfg.EncryptionKey)
-	go hub.Run()
+	hub := handlers.NewHub(db, cfg.EncryptionKey, ps)
+	if err := hub.Subscribe(context.Background()); err != nil {
+		log.Fatal("Failed to subscribe WebSocket Hub:", err)
+	}
 	log.Println("WebSocket Hub started")
 
 	// Setup Gin
@@ -83,6 +98,27 @@ func main() {
 	// Initialize handlers
 	h := handlers.New(db, cfg, s3Storage, hub)
 
+	// Load exchange rates from file (if configured)
+	if cfg.ExchangeRatesFile != "" {
+		rates, err := currency.LoadRatesFile(cfg.ExchangeRatesFile)
+		if err != nil {
+			log.Printf("Failed to load exchange rates: %v", err)
+		} else if err := h.ImportExchangeRates(rates); err != nil {
+			log.Printf("Failed to save exchange rates: %v", err)
+		} else {
+			log.Printf("Loaded %d exchange rates from %s", len(rates), cfg.ExchangeRatesFile)
+		}
+	}
+
+	// Poll carrier tracking statuses
+	if h.Tracker.Len() > 0 {
+		go h.RunTrackingPoller(cfg.TrackingPollInterval)
+		log.Println("Tracking poller started")
+	}
+
+	// Reveal santas when raffle events arrive
+	go h.RunRevealScheduler(time.Minute)
+
 	// API routes
 	api := r.Group("/api")
 	{
@@ -119,6 +155,22 @@ func main() {
 			protected.GET("/profile", h.GetProfile)
 			protected.PUT("/profile", h.UpdateProfile)
 
+			// Default profile wishlist
+			protected.GET("/profile/wishlist", h.GetProfileWishlist)
+			protected.POST("/profile/wishlist", h.CreateProfileWishlistItem)
+			protected.PUT("/profile/wishlist/:itemId", h.UpdateProfileWishlistItem)
+			protected.DELETE("/profile/wishlist/:itemId", h.DeleteProfileWishlistItem)
+
+			// Address book and profile presets
+			protected.GET("/profile/addresses", h.GetSavedAddresses)
+			protected.POST("/profile/addresses", h.CreateSavedAddress)
+			protected.PUT("/profile/addresses/:addressId", h.UpdateSavedAddress)
+			protected.DELETE("/profile/addresses/:addressId", h.DeleteSavedAddress)
+			protected.GET("/profile/presets", h.GetProfilePresets)
+			protected.POST("/profile/presets", h.CreateProfilePreset)
+			protected.PUT("/profile/presets/:presetId", h.UpdateProfilePreset)
+			protected.DELETE("/profile/presets/:presetId", h.DeleteProfilePreset)
+
 			// Raffles
 			protected.GET("/raffles", h.GetRaffles)
 			protected.POST("/raffles", h.CreateRaffle)
@@ -128,11 +180,42 @@ func main() {
 			protected.POST("/raffles/:id/draw", h.DrawNames)
 			protected.GET("/raffles/:id/my-assignment", h.GetMyAssignment)
 			protected.DELETE("/raffles/:id/members/:memberId", h.RemoveMember)
+			protected.PUT("/raffles/:id/office-address", h.UpdateOfficeAddress)
+			protected.PUT("/raffles/:id/questions", h.UpdateProfileQuestions)
+			protected.PUT("/raffles/:id/required-fields", h.UpdateRequiredFields)
+			protected.GET("/raffles/:id/labels", h.ExportLabels)
 
 			// Participant profile in raffle
 			protected.GET("/raffles/:id/my-profile", h.GetMyProfile)
 			protected.PUT("/raffles/:id/my-profile", h.UpdateMyProfile)
 			protected.GET("/raffles/:id/my-giftee", h.GetMyGiftee)
+			protected.GET("/raffles/:id/my-giftee/label", h.GetGifteeLabel)
+			protected.POST("/raffles/:id/my-giftee/wishlist/:itemId/reserve", h.ReserveGifteeWishlistItem)
+			protected.DELETE("/raffles/:id/my-giftee/wishlist/:itemId/reserve", h.UnreserveGifteeWishlistItem)
+
+			// Participant wishlist in raffle
+			protected.GET("/raffles/:id/my-wishlist", h.GetMyWishlist)
+			protected.POST("/raffles/:id/my-wishlist", h.CreateMyWishlistItem)
+			protected.PUT("/raffles/:id/my-wishlist/:itemId", h.UpdateMyWishlistItem)
+			protected.DELETE("/raffles/:id/my-wishlist/:itemId", h.DeleteMyWishlistItem)
+
+			// Gift shipment tracking
+			protected.GET("/raffles/:id/gift/outgoing", h.GetOutgoingGift)
+			protected.PUT("/raffles/:id/gift/outgoing", h.UpdateOutgoingGift)
+			protected.GET("/raffles/:id/gift/incoming", h.GetIncomingGift)
+			protected.POST("/raffles/:id/gift/incoming/received", h.ConfirmGiftReceived)
+			protected.GET("/raffles/:id/thanks", h.GetThankYouGallery)
+			protected.GET("/raffles/:id/gifts/summary", h.GetGiftSummary)
+
+			// Reveal
+			protected.POST("/raffles/:id/reveal", h.RevealRaffle)
+			protected.GET("/raffles/:id/chain", h.GetGiftChain)
+
+			// Guess your santa
+			protected.GET("/raffles/:id/guess", h.GetMySantaGuess)
+			protected.PUT("/raffles/:id/guess", h.SubmitSantaGuess)
+			protected.GET("/raffles/:id/leaderboard", h.GetRaffleLeaderboard)
+			protected.GET("/series/:seriesId/leaderboard", h.GetSeriesLeaderboard)
 
 			// Exclusions management (only for raffle owner)
 			protected.GET("/raffles/:id/exclusions", h.GetExclusions)
@@ -142,10 +225,29 @@ func main() {
 			// Chat REST API (protected)
 			protected.GET("/raffles/:id/chat/giftee", h.GetChatWithGiftee)
 			protected.GET("/raffles/:id/chat/santa", h.GetChatWithSanta)
+			protected.POST("/raffles/:id/chat/giftee/read", h.MarkGifteeChatRead)
+			protected.POST("/raffles/:id/chat/santa/read", h.MarkSantaChatRead)
 			protected.GET("/raffles/:id/chat/unread", h.GetUnreadCount)
+			protected.POST("/raffles/:id/chat/ticket", h.CreateChatTicket)
+			protected.PUT("/raffles/:id/chat/messages/:messageId", h.EditChatMessage)
+			protected.DELETE("/raffles/:id/chat/messages/:messageId", h.DeleteChatMessage)
+			protected.GET("/raffles/:id/chat/messages/:messageId/history", h.GetChatMessageHistory)
+			protected.POST("/raffles/:id/chat/attachments", h.SendChatAttachment)
+			protected.GET("/raffles/:id/chat/messages/:messageId/attachment", h.GetChatAttachmentURL)
+
+			// Exchange rates
+			protected.GET("/exchange-rates", h.GetExchangeRates)
+		}
+
+		// Admin routes
+		admin := api.Group("/admin")
+		admin.Use(middleware.Auth(cfg.JWTSecret), middleware.Admin(cfg.AdminUserIDs))
+		{
+			admin.PUT("/exchange-rates", h.UpdateExchangeRates)
+			admin.POST("/exchange-rates/reload", h.ReloadExchangeRates)
 		}
 
-		// WebSocket endpoint (auth via query parameter, not middleware)
+		// WebSocket endpoint (auth via one-time ?ticket= from /chat/ticket, not middleware)
 		api.GET("/raffles/:id/chat/ws", h.HandleWebSocket)
 	}
 
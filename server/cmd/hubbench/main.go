@@ -0,0 +1,227 @@
+// hubbench - нагрузочный прогон WebSocket хаба чата: тысячи соединений по комнатам розыгрышей,
+// точечная рассылка событий участникам и поведение при медленных клиентах.
+//
+//	go run ./cmd/hubbench -groups 200 -members 20 -events 50000 -slow 20
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"secret-santa/internal/handlers"
+	"secret-santa/internal/pubsub"
+)
+
+// benchEvent - событие, которое хаб доставляет участнику
+type benchEvent struct {
+	Type    string `json:"type"` // "bench"
+	Seq     int    `json:"seq"`
+	SentAt  int64  `json:"sent_at"` // UnixNano
+	Padding string `json:"padding,omitempty"`
+}
+
+type member struct {
+	groupID  uuid.UUID
+	memberID uuid.UUID
+	slow     bool
+}
+
+func main() {
+	groups := flag.Int("groups", 100, "number of raffles (hub rooms)")
+	perGroup := flag.Int("members", 20, "connected members per raffle")
+	events := flag.Int("events", 20000, "events to deliver")
+	slow := flag.Int("slow", 10, "members that never read from their socket")
+	payload := flag.Int("payload", 256, "event padding in bytes")
+	flood := flag.Int("flood", 2000, "events sent to each slow member after the main run")
+	verbose := flag.Bool("v", false, "show hub logs")
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	ps := pubsub.NewMemory()
+	defer ps.Close()
+
+	hub := handlers.NewHub(nil, nil, ps)
+	if err := hub.Subscribe(context.Background()); err != nil {
+		fatal("subscribe: %v", err)
+	}
+
+	upgrader := websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		info := handlers.ClientInfo{
+			UserID:   uuid.New(),
+			GroupID:  uuid.MustParse(q.Get("group")),
+			MemberID: uuid.MustParse(q.Get("member")),
+			SantaID:  uuid.MustParse(q.Get("santa")),
+			GifteeID: uuid.MustParse(q.Get("giftee")),
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.Attach(conn, info)
+	}))
+	defer server.Close()
+
+	// Участники по кругу: i дарит i+1
+	var members []member
+	for g := 0; g < *groups; g++ {
+		groupID := uuid.New()
+		for i := 0; i < *perGroup; i++ {
+			members = append(members, member{groupID: groupID, memberID: uuid.New()})
+		}
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, i := range rng.Perm(len(members))[:min(*slow, len(members))] {
+		members[i].slow = true
+	}
+
+	var (
+		latMu     sync.Mutex
+		latencies []time.Duration
+		received  atomic.Int64
+		closed    atomic.Int64
+	)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+
+	start := time.Now()
+	conns := make([]*websocket.Conn, len(members))
+	for i, m := range members {
+		base := (i / *perGroup) * *perGroup
+		n := *perGroup
+		giftee := members[base+(i-base+1)%n].memberID
+		santa := members[base+(i-base+n-1)%n].memberID
+
+		url := fmt.Sprintf("%s/?group=%s&member=%s&santa=%s&giftee=%s", wsURL, m.groupID, m.memberID, santa, giftee)
+		conn, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			fatal("dial %d: %v (raise ulimit -n?)", i, err)
+		}
+		conns[i] = conn
+
+		if m.slow {
+			continue // Медленный клиент: никогда не читает
+		}
+		go func(conn *websocket.Conn) {
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					closed.Add(1)
+					return
+				}
+				now := time.Now().UnixNano()
+				for _, line := range bytes.Split(data, []byte{'\n'}) {
+					var e benchEvent
+					if json.Unmarshal(line, &e) != nil || e.Type != "bench" {
+						continue
+					}
+					received.Add(1)
+					latMu.Lock()
+					latencies = append(latencies, time.Duration(now-e.SentAt))
+					latMu.Unlock()
+				}
+			}
+		}(conn)
+	}
+	setup := time.Since(start)
+	rooms, clients := hub.Stats()
+
+	fmt.Printf("sockets:      %d (%d rooms, %d registered, %d slow)\n", len(members), rooms, clients, *slow)
+	fmt.Printf("connect:      %s (%.0f conn/s)\n", setup.Round(time.Millisecond), float64(len(members))/setup.Seconds())
+
+	// Рассылка: каждое событие - одному случайному участнику (как NotifyMember)
+	padding := strings.Repeat("x", *payload)
+	expected := 0
+	start = time.Now()
+	for seq := 0; seq < *events; seq++ {
+		m := members[rng.Intn(len(members))]
+		if !m.slow {
+			expected++
+		}
+		hub.NotifyMember(m.groupID, m.memberID, benchEvent{
+			Type:    "bench",
+			Seq:     seq,
+			SentAt:  time.Now().UnixNano(),
+			Padding: padding,
+		})
+	}
+	publish := time.Since(start)
+
+	// Ждем доставки всем быстрым клиентам
+	deadline := time.Now().Add(30 * time.Second)
+	for received.Load() < int64(expected) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	total := time.Since(start)
+
+	fmt.Printf("publish:      %d events in %s (%.0f events/s)\n", *events, publish.Round(time.Millisecond), float64(*events)/publish.Seconds())
+	fmt.Printf("delivered:    %d/%d to fast clients in %s\n", received.Load(), expected, total.Round(time.Millisecond))
+
+	latMu.Lock()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	if len(latencies) > 0 {
+		pct := func(p float64) time.Duration { return latencies[int(p*float64(len(latencies)-1))] }
+		fmt.Printf("latency:      p50=%s p95=%s p99=%s max=%s\n",
+			pct(0.50).Round(time.Microsecond), pct(0.95).Round(time.Microsecond),
+			pct(0.99).Round(time.Microsecond), latencies[len(latencies)-1].Round(time.Microsecond))
+	}
+	latMu.Unlock()
+
+	// Заваливаем медленных клиентов: их очередь переполняется, хаб должен их отключить
+	// (после таймаута записи), не задерживая доставку остальным
+	start = time.Now()
+	for _, m := range members {
+		if !m.slow {
+			continue
+		}
+		for i := 0; i < *flood; i++ {
+			hub.NotifyMember(m.groupID, m.memberID, benchEvent{Type: "flood", Padding: padding})
+		}
+	}
+	deadline = time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, clients = hub.Stats(); clients <= len(members)-*slow {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	fmt.Printf("flood:        %d events per slow client, disconnected after %s\n", *flood, time.Since(start).Round(time.Millisecond))
+
+	_, clients = hub.Stats()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Printf("after:        %d registered (%d slow disconnected), %d fast closed\n", clients, len(members)-clients, closed.Load())
+	fmt.Printf("runtime:      %d goroutines, %d MiB heap\n", runtime.NumGoroutine(), mem.HeapAlloc>>20)
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+func fatal(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "hubbench: "+format+"\n", args...)
+	os.Exit(1)
+}
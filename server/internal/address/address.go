@@ -0,0 +1,166 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Address - почтовый адрес участника на местном языке и на английском
+type Address struct {
+	Line1      string
+	Line2      string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string // ISO 3166-1 alpha-2
+
+	Line1En  string
+	Line2En  string
+	CityEn   string
+	RegionEn string
+}
+
+// Rules - требования к адресу в конкретной стране
+type Rules struct {
+	PostalCode     *regexp.Regexp // nil - индексов в стране нет или формат не проверяем
+	PostalExample  string
+	RegionRequired bool // Штат, провинция, префектура и т.п.
+}
+
+// countryRules - форматы индексов и обязательность региона по странам
+var countryRules = map[string]Rules{
+	"RU": {PostalCode: regexp.MustCompile(`^\d{6}$`), PostalExample: "101000"},
+	"BY": {PostalCode: regexp.MustCompile(`^\d{6}$`), PostalExample: "220030"},
+	"KZ": {PostalCode: regexp.MustCompile(`^(\d{6}|[A-Z]\d{2}[A-Z]\d[A-Z]\d)$`), PostalExample: "050000"},
+	"UA": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "01001"},
+	"AM": {PostalCode: regexp.MustCompile(`^\d{4}$`), PostalExample: "0010"},
+	"GE": {PostalCode: regexp.MustCompile(`^\d{4}$`), PostalExample: "0105"},
+	"AZ": {PostalCode: regexp.MustCompile(`^(AZ ?)?\d{4}$`), PostalExample: "AZ 1000"},
+	"KG": {PostalCode: regexp.MustCompile(`^\d{6}$`), PostalExample: "720001"},
+	"UZ": {PostalCode: regexp.MustCompile(`^\d{6}$`), PostalExample: "100000"},
+	"MD": {PostalCode: regexp.MustCompile(`^(MD-?)?\d{4}$`), PostalExample: "MD-2001"},
+	"RS": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "11000"},
+	"TR": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "34000"},
+	"US": {PostalCode: regexp.MustCompile(`^\d{5}(-\d{4})?$`), PostalExample: "10001", RegionRequired: true},
+	"CA": {PostalCode: regexp.MustCompile(`^[A-Z]\d[A-Z] \d[A-Z]\d$`), PostalExample: "K1A 0B1", RegionRequired: true},
+	"MX": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "06000", RegionRequired: true},
+	"BR": {PostalCode: regexp.MustCompile(`^\d{5}-?\d{3}$`), PostalExample: "01000-000", RegionRequired: true},
+	"AR": {PostalCode: regexp.MustCompile(`^([A-Z]\d{4}[A-Z]{3}|\d{4})$`), PostalExample: "C1000AAA", RegionRequired: true},
+	"GB": {PostalCode: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? \d[A-Z]{2}$`), PostalExample: "SW1A 1AA"},
+	"IE": {PostalCode: regexp.MustCompile(`^[A-Z]\d[\dW] ?[A-Z\d]{4}$`), PostalExample: "D02 X285"},
+	"DE": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "10115"},
+	"FR": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "75001"},
+	"ES": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "28001"},
+	"IT": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "00118", RegionRequired: true},
+	"PT": {PostalCode: regexp.MustCompile(`^\d{4}-\d{3}$`), PostalExample: "1000-001"},
+	"NL": {PostalCode: regexp.MustCompile(`^\d{4} [A-Z]{2}$`), PostalExample: "1011 AB"},
+	"BE": {PostalCode: regexp.MustCompile(`^\d{4}$`), PostalExample: "1000"},
+	"AT": {PostalCode: regexp.MustCompile(`^\d{4}$`), PostalExample: "1010"},
+	"CH": {PostalCode: regexp.MustCompile(`^\d{4}$`), PostalExample: "8001"},
+	"PL": {PostalCode: regexp.MustCompile(`^\d{2}-\d{3}$`), PostalExample: "00-001"},
+	"CZ": {PostalCode: regexp.MustCompile(`^\d{3} ?\d{2}$`), PostalExample: "110 00"},
+	"SE": {PostalCode: regexp.MustCompile(`^\d{3} ?\d{2}$`), PostalExample: "111 22"},
+	"NO": {PostalCode: regexp.MustCompile(`^\d{4}$`), PostalExample: "0150"},
+	"DK": {PostalCode: regexp.MustCompile(`^\d{4}$`), PostalExample: "1050"},
+	"FI": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "00100"},
+	"EE": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "10111"},
+	"LV": {PostalCode: regexp.MustCompile(`^(LV-)?\d{4}$`), PostalExample: "LV-1050"},
+	"LT": {PostalCode: regexp.MustCompile(`^(LT-)?\d{5}$`), PostalExample: "LT-01100"},
+	"IL": {PostalCode: regexp.MustCompile(`^\d{7}$`), PostalExample: "6100000"},
+	"AE": {},
+	"CN": {PostalCode: regexp.MustCompile(`^\d{6}$`), PostalExample: "100000", RegionRequired: true},
+	"JP": {PostalCode: regexp.MustCompile(`^\d{3}-\d{4}$`), PostalExample: "100-0001", RegionRequired: true},
+	"KR": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "03000"},
+	"IN": {PostalCode: regexp.MustCompile(`^\d{6}$`), PostalExample: "110001", RegionRequired: true},
+	"TH": {PostalCode: regexp.MustCompile(`^\d{5}$`), PostalExample: "10100", RegionRequired: true},
+	"AU": {PostalCode: regexp.MustCompile(`^\d{4}$`), PostalExample: "2000", RegionRequired: true},
+	"NZ": {PostalCode: regexp.MustCompile(`^\d{4}$`), PostalExample: "6011"},
+}
+
+// RulesFor возвращает правила для страны (false - страна неизвестна, проверяются только общие ограничения)
+func RulesFor(country string) (Rules, bool) {
+	rules, ok := countryRules[strings.ToUpper(strings.TrimSpace(country))]
+	return rules, ok
+}
+
+// NormalizePostalCode приводит индекс к формату страны (регистр, пробелы)
+func NormalizePostalCode(country, code string) string {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), " "))
+	compact := strings.ReplaceAll(code, " ", "")
+
+	switch strings.ToUpper(country) {
+	case "CA", "GB":
+		// Внутренняя часть индекса - всегда последние 3 символа
+		if len(compact) > 3 {
+			return compact[:len(compact)-3] + " " + compact[len(compact)-3:]
+		}
+	case "NL":
+		if len(compact) == 6 {
+			return compact[:4] + " " + compact[4:]
+		}
+	case "CZ", "SE":
+		// Пробел в середине допустим по формату страны
+		return code
+	}
+
+	// Цифровые индексы пишем без пробелов ("420 111" -> "420111")
+	if strings.Trim(compact, "0123456789") == "" {
+		return compact
+	}
+	return code
+}
+
+// Validate проверяет адрес по правилам страны.
+// Пустой адрес допустим: участник может заполнить только вишлист.
+func Validate(a Address) error {
+	if a.Line1 == "" && a.City == "" && a.PostalCode == "" {
+		return nil
+	}
+
+	rules, ok := RulesFor(a.Country)
+	if !ok {
+		return nil
+	}
+
+	if rules.PostalCode != nil && a.PostalCode != "" {
+		if !rules.PostalCode.MatchString(NormalizePostalCode(a.Country, a.PostalCode)) {
+			return fmt.Errorf("invalid postal code for %s (expected format like %s)", strings.ToUpper(a.Country), rules.PostalExample)
+		}
+	}
+
+	if rules.RegionRequired && strings.TrimSpace(a.Region) == "" {
+		return errors.New("region is required for " + strings.ToUpper(a.Country))
+	}
+
+	return nil
+}
+
+// Normalize приводит индекс к формату страны и заполняет английские поля транслитерацией
+// кириллицы (ГОСТ Р 52535.1-2006 / ICAO Doc 9303). prev - адрес до изменения: английское поле,
+// совпадающее с транслитерацией прежнего местного, заполнено автоматически и пересчитывается,
+// а введенное участником вручную не трогается.
+func Normalize(a *Address, prev Address) {
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	if a.PostalCode != "" {
+		a.PostalCode = NormalizePostalCode(a.Country, a.PostalCode)
+	}
+
+	fill := func(en *string, local, prevLocal string) {
+		generated := HasCyrillic(prevLocal) && *en == Transliterate(prevLocal)
+		if strings.TrimSpace(*en) != "" && !generated {
+			return
+		}
+		if HasCyrillic(local) {
+			*en = Transliterate(local)
+		} else if generated {
+			// Местное поле больше не на кириллице - старая транслитерация не нужна
+			*en = ""
+		}
+	}
+	fill(&a.Line1En, a.Line1, prev.Line1)
+	fill(&a.Line2En, a.Line2, prev.Line2)
+	fill(&a.CityEn, a.City, prev.City)
+	fill(&a.RegionEn, a.Region, prev.Region)
+}
@@ -0,0 +1,157 @@
+package address
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Щукинская", "Shchukinskaia"},
+		{"ЖК ЁЛКИ", "ZHK ELKI"},
+		{"Жуковский", "Zhukovskii"},
+		{"ул. Льва Толстого, д. 16", "ul. Lva Tolstogo, d. 16"},
+		{"Объезд", "Obieezd"},
+		{"Київ", "Kiiv"},
+		{"Ґанок", "Ganok"},
+		{"Мінск, вул. Ўзгорак", "Minsk, vul. Uzgorak"},
+		{"Baker Street 221b", "Baker Street 221b"}, // Латиница не меняется
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Transliterate(tt.in); got != tt.want {
+			t.Errorf("Transliterate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizePostalCode(t *testing.T) {
+	tests := []struct {
+		country, code, want string
+	}{
+		{"RU", "101 000", "101000"},
+		{"ru", " 101000 ", "101000"},
+		{"CA", "k1a0b1", "K1A 0B1"},
+		{"CA", "K1A  0B1", "K1A 0B1"},
+		{"GB", "sw1a1aa", "SW1A 1AA"},
+		{"GB", "m11ae", "M1 1AE"},
+		{"NL", "1011ab", "1011 AB"},
+		{"CZ", "110 00", "110 00"}, // Пробел допустим по формату
+		{"SE", "11122", "11122"},
+		{"US", "10001-1234", "10001-1234"},
+		{"PT", "1000 - 001", "1000 - 001"}, // Не только цифры - пробелы не убираем
+		{"AZ", "az 1000", "AZ 1000"},
+		{"", "12 34", "1234"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizePostalCode(tt.country, tt.code); got != tt.want {
+			t.Errorf("NormalizePostalCode(%q, %q) = %q, want %q", tt.country, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		address Address
+		wantErr string // Подстрока ошибки, "" - адрес корректен
+	}{
+		{"empty address", Address{Country: "US"}, ""},
+		{"valid RU", Address{Line1: "ул. Тверская, 1", City: "Москва", PostalCode: "101000", Country: "RU"}, ""},
+		{"RU code with spaces", Address{City: "Москва", PostalCode: "101 000", Country: "RU"}, ""},
+		{"invalid RU code", Address{City: "Москва", PostalCode: "1010", Country: "RU"}, "expected format like 101000"},
+		{"lowercase country", Address{City: "Ottawa", PostalCode: "k1a0b1", Region: "ON", Country: "ca"}, ""},
+		{"region required", Address{City: "New York", PostalCode: "10001", Country: "US"}, "region is required for US"},
+		{"blank region", Address{City: "New York", PostalCode: "10001", Region: "  ", Country: "US"}, "region is required"},
+		{"valid US", Address{City: "New York", PostalCode: "10001", Region: "NY", Country: "US"}, ""},
+		{"no postal codes", Address{Line1: "Sheikh Zayed Rd", City: "Dubai", Country: "AE"}, ""},
+		{"missing postal code", Address{Line1: "Unter den Linden 1", City: "Berlin", Country: "DE"}, ""},
+		{"unknown country", Address{City: "Atlantis", PostalCode: "???", Country: "XX"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.address)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		address    Address
+		prev       Address
+		wantCity   string
+		wantCityEn string
+	}{
+		{
+			name:       "fills empty English field",
+			address:    Address{City: "Москва"},
+			wantCity:   "Москва",
+			wantCityEn: "Moskva",
+		},
+		{
+			name:       "keeps English field typed by the user",
+			address:    Address{City: "Москва", CityEn: "Moscow"},
+			prev:       Address{City: "Москва"},
+			wantCity:   "Москва",
+			wantCityEn: "Moscow",
+		},
+		{
+			name:       "regenerates stale transliteration",
+			address:    Address{City: "Казань", CityEn: "Moskva"},
+			prev:       Address{City: "Москва"},
+			wantCity:   "Казань",
+			wantCityEn: "Kazan",
+		},
+		{
+			name:       "keeps manual English field after a local edit",
+			address:    Address{City: "Казань", CityEn: "Moscow"},
+			prev:       Address{City: "Москва"},
+			wantCity:   "Казань",
+			wantCityEn: "Moscow",
+		},
+		{
+			name:       "clears transliteration when the local field is no longer Cyrillic",
+			address:    Address{City: "Paris", CityEn: "Moskva"},
+			prev:       Address{City: "Москва"},
+			wantCity:   "Paris",
+			wantCityEn: "",
+		},
+		{
+			name:       "leaves Latin addresses alone",
+			address:    Address{City: "Paris"},
+			wantCity:   "Paris",
+			wantCityEn: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.address
+			Normalize(&a, tt.prev)
+			if a.City != tt.wantCity || a.CityEn != tt.wantCityEn {
+				t.Errorf("Normalize() city = %q / %q, want %q / %q", a.City, a.CityEn, tt.wantCity, tt.wantCityEn)
+			}
+		})
+	}
+
+	a := Address{PostalCode: "k1a0b1", Country: " ca "}
+	Normalize(&a, Address{})
+	if a.Country != "CA" || a.PostalCode != "K1A 0B1" {
+		t.Errorf("Normalize() = %q %q, want CA K1A 0B1", a.Country, a.PostalCode)
+	}
+}
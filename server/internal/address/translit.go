@@ -0,0 +1,61 @@
+package address
+
+import (
+	"strings"
+	"unicode"
+)
+
+// icaoTable - транслитерация кириллицы по ГОСТ Р 52535.1-2006 (совпадает с ICAO Doc 9303)
+var icaoTable = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu",
+	'я': "ia",
+
+	// Украинский и белорусский алфавиты (ICAO)
+	'і': "i", 'ї': "i", 'є': "ie", 'ґ': "g", 'ў': "u",
+}
+
+// HasCyrillic проверяет, есть ли в строке кириллица
+func HasCyrillic(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Transliterate переводит кириллицу в латиницу, сохраняя регистр:
+// "Щукинская" -> "Shchukinskaia", "ЖК ЁЛКИ" -> "ZHK ELKI"
+func Transliterate(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i, r := range runes {
+		latin, ok := icaoTable[unicode.ToLower(r)]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		if latin == "" || !unicode.IsUpper(r) {
+			b.WriteString(latin)
+			continue
+		}
+
+		// Заглавная буква: в слове из заглавных - целиком заглавными, иначе только первая
+		if isUpperAt(runes, i-1) || isUpperAt(runes, i+1) {
+			b.WriteString(strings.ToUpper(latin))
+		} else {
+			b.WriteString(strings.ToUpper(latin[:1]) + latin[1:])
+		}
+	}
+
+	return b.String()
+}
+
+func isUpperAt(runes []rune, i int) bool {
+	return i >= 0 && i < len(runes) && unicode.IsUpper(runes[i])
+}
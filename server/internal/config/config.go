@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -39,6 +40,20 @@ type Config struct {
 
 	// Шифрование сообщений в БД (32 байта)
 	EncryptionKey []byte
+
+	// Администраторы (ID пользователей) для служебных эндпоинтов
+	AdminUserIDs []string
+
+	// JSON файл с курсами валют (загружается при старте)
+	ExchangeRatesFile string
+
+	// Агрегатор трекинга посылок (опрос статусов отключен, если URL не задан)
+	TrackingAPIURL       string
+	TrackingAPIKey       string
+	TrackingPollInterval time.Duration
+
+	// Рассылка событий чата между экземплярами API: "postgres" (LISTEN/NOTIFY) или "memory" (один экземпляр)
+	HubPubSub string
 }
 
 func Load() *Config {
@@ -89,6 +104,17 @@ func Load() *Config {
 
 		// Шифрование (base64 строка, которая декодируется в 32 байта)
 		EncryptionKey: decodeEncryptionKey(getEnv("ENCRYPTION_KEY", "ZGV2LWVuY3J5cHRpb24ta2V5LTMyLWJ5dGVzISE=")),
+
+		// Администрирование
+		AdminUserIDs:      splitList(os.Getenv("ADMIN_USER_IDS")),
+		ExchangeRatesFile: os.Getenv("EXCHANGE_RATES_FILE"),
+
+		// Трекинг посылок
+		TrackingAPIURL:       os.Getenv("TRACKING_API_URL"),
+		TrackingAPIKey:       os.Getenv("TRACKING_API_KEY"),
+		TrackingPollInterval: getDuration("TRACKING_POLL_INTERVAL", time.Hour),
+
+		HubPubSub: getEnv("HUB_PUBSUB", "postgres"),
 	}
 }
 
@@ -99,6 +125,31 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getDuration читает длительность в формате Go ("30m", "1h")
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: invalid %s=%q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// splitList разбивает строку через запятую, пропуская пустые элементы
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 // decodeEncryptionKey декодирует base64 строку в 32 байта
 func decodeEncryptionKey(base64Key string) []byte {
 	key, err := base64.StdEncoding.DecodeString(base64Key)
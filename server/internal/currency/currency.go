@@ -0,0 +1,244 @@
+package currency
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BaseCurrency - валюта, относительно которой хранятся курсы
+const BaseCurrency = "USD"
+
+// MaxAmount - верхняя граница суммы бюджета (защита от мусорных значений)
+const MaxAmount int64 = 1_000_000_000
+
+// codes - действующие коды ISO 4217
+var codes = map[string]bool{
+	"AED": true, "AFN": true, "ALL": true, "AMD": true, "ANG": true, "AOA": true, "ARS": true, "AUD": true,
+	"AWG": true, "AZN": true, "BAM": true, "BBD": true, "BDT": true, "BGN": true, "BHD": true, "BIF": true,
+	"BMD": true, "BND": true, "BOB": true, "BRL": true, "BSD": true, "BTN": true, "BWP": true, "BYN": true,
+	"BZD": true, "CAD": true, "CDF": true, "CHF": true, "CLP": true, "CNY": true, "COP": true, "CRC": true,
+	"CUP": true, "CVE": true, "CZK": true, "DJF": true, "DKK": true, "DOP": true, "DZD": true, "EGP": true,
+	"ERN": true, "ETB": true, "EUR": true, "FJD": true, "FKP": true, "GBP": true, "GEL": true, "GHS": true,
+	"GIP": true, "GMD": true, "GNF": true, "GTQ": true, "GYD": true, "HKD": true, "HNL": true, "HTG": true,
+	"HUF": true, "IDR": true, "ILS": true, "INR": true, "IQD": true, "IRR": true, "ISK": true, "JMD": true,
+	"JOD": true, "JPY": true, "KES": true, "KGS": true, "KHR": true, "KMF": true, "KPW": true, "KRW": true,
+	"KWD": true, "KYD": true, "KZT": true, "LAK": true, "LBP": true, "LKR": true, "LRD": true, "LSL": true,
+	"LYD": true, "MAD": true, "MDL": true, "MGA": true, "MKD": true, "MMK": true, "MNT": true, "MOP": true,
+	"MRU": true, "MUR": true, "MVR": true, "MWK": true, "MXN": true, "MYR": true, "MZN": true, "NAD": true,
+	"NGN": true, "NIO": true, "NOK": true, "NPR": true, "NZD": true, "OMR": true, "PAB": true, "PEN": true,
+	"PGK": true, "PHP": true, "PKR": true, "PLN": true, "PYG": true, "QAR": true, "RON": true, "RSD": true,
+	"RUB": true, "RWF": true, "SAR": true, "SBD": true, "SCR": true, "SDG": true, "SEK": true, "SGD": true,
+	"SHP": true, "SLE": true, "SOS": true, "SRD": true, "SSP": true, "STN": true, "SYP": true, "SZL": true,
+	"THB": true, "TJS": true, "TMT": true, "TND": true, "TOP": true, "TRY": true, "TTD": true, "TWD": true,
+	"TZS": true, "UAH": true, "UGX": true, "USD": true, "UYU": true, "UZS": true, "VES": true, "VND": true,
+	"VUV": true, "WST": true, "XAF": true, "XCD": true, "XOF": true, "XPF": true, "YER": true, "ZAR": true,
+	"ZMW": true, "ZWL": true,
+}
+
+// countryCurrency - валюта по умолчанию для страны (ISO 3166-1 alpha-2)
+var countryCurrency = map[string]string{
+	"RU": "RUB", "BY": "BYN", "KZ": "KZT", "UA": "UAH", "AM": "AMD", "GE": "GEL", "AZ": "AZN",
+	"KG": "KGS", "UZ": "UZS", "TJ": "TJS", "MD": "MDL", "RS": "RSD", "TR": "TRY",
+	"US": "USD", "CA": "CAD", "MX": "MXN", "BR": "BRL", "AR": "ARS", "CL": "CLP", "CO": "COP",
+	"GB": "GBP", "CH": "CHF", "NO": "NOK", "SE": "SEK", "DK": "DKK", "PL": "PLN", "CZ": "CZK",
+	"HU": "HUF", "RO": "RON", "BG": "BGN", "IS": "ISK",
+	"ES": "EUR", "DE": "EUR", "FR": "EUR", "IT": "EUR", "PT": "EUR", "NL": "EUR", "BE": "EUR",
+	"AT": "EUR", "IE": "EUR", "FI": "EUR", "GR": "EUR", "CY": "EUR", "EE": "EUR", "LV": "EUR",
+	"LT": "EUR", "LU": "EUR", "MT": "EUR", "SK": "EUR", "SI": "EUR", "HR": "EUR", "ME": "EUR",
+	"IL": "ILS", "AE": "AED", "IN": "INR", "CN": "CNY", "JP": "JPY", "KR": "KRW", "TH": "THB",
+	"VN": "VND", "ID": "IDR", "SG": "SGD", "HK": "HKD", "AU": "AUD", "NZ": "NZD", "ZA": "ZAR",
+}
+
+// IsValid проверяет, что код - действующая валюта ISO 4217
+func IsValid(code string) bool {
+	return codes[strings.ToUpper(code)]
+}
+
+// ForCountry возвращает валюту страны или пустую строку, если страна неизвестна
+func ForCountry(country string) string {
+	return countryCurrency[strings.ToUpper(strings.TrimSpace(country))]
+}
+
+// Budget - бюджет подарка в целых единицах валюты
+type Budget struct {
+	Min      *int64
+	Max      *int64
+	Currency string
+}
+
+// Validate проверяет корректность диапазона и кода валюты
+func (b Budget) Validate() error {
+	if b.Min == nil && b.Max == nil {
+		return errors.New("budget amount is required")
+	}
+	if !IsValid(b.Currency) {
+		return errors.New("invalid currency code")
+	}
+	for _, amount := range []*int64{b.Min, b.Max} {
+		if amount == nil {
+			continue
+		}
+		if *amount < 0 {
+			return errors.New("budget amount cannot be negative")
+		}
+		if *amount > MaxAmount {
+			return errors.New("budget amount is too large")
+		}
+	}
+	if b.Min != nil && b.Max != nil && *b.Min > *b.Max {
+		return errors.New("budget minimum is greater than maximum")
+	}
+	return nil
+}
+
+// Contains проверяет, что сумма (в валюте бюджета) попадает в диапазон
+func (b Budget) Contains(amount int64) bool {
+	if b.Min != nil && amount < *b.Min {
+		return false
+	}
+	if b.Max != nil && amount > *b.Max {
+		return false
+	}
+	return true
+}
+
+// String форматирует бюджет для отображения: "1000-3000 RUB", "от 1000 RUB", "до 3000 RUB"
+func (b Budget) String() string {
+	switch {
+	case b.Min != nil && b.Max != nil:
+		return fmt.Sprintf("%d-%d %s", *b.Min, *b.Max, b.Currency)
+	case b.Min != nil:
+		return fmt.Sprintf("от %d %s", *b.Min, b.Currency)
+	case b.Max != nil:
+		return fmt.Sprintf("до %d %s", *b.Max, b.Currency)
+	}
+	return ""
+}
+
+var (
+	rangeRe = regexp.MustCompile(`^(\d+)\s*-\s*(\d+)\s+([A-Za-z]{3})$`)
+	fromRe  = regexp.MustCompile(`^(?i:от|from)\s+(\d+)\s+([A-Za-z]{3})$`)
+	toRe    = regexp.MustCompile(`^(?i:до|up to|to)\s+(\d+)\s+([A-Za-z]{3})$`)
+	exactRe = regexp.MustCompile(`^(\d+)\s+([A-Za-z]{3})$`)
+)
+
+// ParseBudget разбирает строку старого формата ("1000-3000 RUB", "от 1000 RUB", "до 3000 RUB")
+func ParseBudget(s string) (Budget, error) {
+	s = strings.TrimSpace(s)
+
+	parse := func(v string) *int64 {
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return &n
+	}
+
+	var b Budget
+	if m := rangeRe.FindStringSubmatch(s); m != nil {
+		b = Budget{Min: parse(m[1]), Max: parse(m[2]), Currency: m[3]}
+	} else if m := fromRe.FindStringSubmatch(s); m != nil {
+		b = Budget{Min: parse(m[1]), Currency: m[2]}
+	} else if m := toRe.FindStringSubmatch(s); m != nil {
+		b = Budget{Max: parse(m[1]), Currency: m[2]}
+	} else if m := exactRe.FindStringSubmatch(s); m != nil {
+		b = Budget{Min: parse(m[1]), Max: parse(m[1]), Currency: m[2]}
+	} else {
+		return Budget{}, errors.New("unrecognized budget format")
+	}
+
+	b.Currency = strings.ToUpper(b.Currency)
+	if err := b.Validate(); err != nil {
+		return Budget{}, err
+	}
+	return b, nil
+}
+
+// Rates - курсы валют: сколько единиц валюты стоит 1 единица BaseCurrency
+type Rates map[string]float64
+
+// Convert переводит сумму из одной валюты в другую с округлением до целых.
+// Возвращает false, если курс одной из валют неизвестен.
+func (r Rates) Convert(amount int64, from, to string) (int64, bool) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return amount, true
+	}
+
+	fromRate, ok := r.rate(from)
+	if !ok {
+		return 0, false
+	}
+	toRate, ok := r.rate(to)
+	if !ok {
+		return 0, false
+	}
+
+	return int64(math.Round(float64(amount) / fromRate * toRate)), true
+}
+
+// ConvertBudget переводит обе границы бюджета в другую валюту
+func (r Rates) ConvertBudget(b Budget, to string) (Budget, bool) {
+	out := Budget{Currency: strings.ToUpper(to)}
+	for _, pair := range []struct {
+		src *int64
+		dst **int64
+	}{{b.Min, &out.Min}, {b.Max, &out.Max}} {
+		if pair.src == nil {
+			continue
+		}
+		converted, ok := r.Convert(*pair.src, b.Currency, to)
+		if !ok {
+			return Budget{}, false
+		}
+		*pair.dst = &converted
+	}
+	return out, true
+}
+
+func (r Rates) rate(code string) (float64, bool) {
+	if code == BaseCurrency {
+		return 1, true
+	}
+	rate, ok := r[code]
+	return rate, ok && rate > 0
+}
+
+// ratesFile - формат файла с курсами
+type ratesFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// LoadRatesFile читает курсы из JSON файла вида {"base": "USD", "rates": {"RUB": 92.5}}
+func LoadRatesFile(path string) (Rates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	var f ratesFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+
+	if f.Base != "" && !strings.EqualFold(f.Base, BaseCurrency) {
+		return nil, fmt.Errorf("rates file base must be %s, got %s", BaseCurrency, f.Base)
+	}
+
+	rates := make(Rates, len(f.Rates))
+	for code, rate := range f.Rates {
+		code = strings.ToUpper(code)
+		if !IsValid(code) {
+			return nil, fmt.Errorf("invalid currency code in rates file: %s", code)
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %v", code, rate)
+		}
+		rates[code] = rate
+	}
+	return rates, nil
+}
@@ -10,17 +10,20 @@ import (
 	"gorm.io/gorm"
 )
 
-func Connect(cfg *config.Config) (*gorm.DB, error) {
+// DSN - строка подключения к Postgres (также для выделенных соединений LISTEN/NOTIFY)
+func DSN(cfg *config.Config) string {
 	sslmode := "disable"
 	if cfg.Env == "production" {
 		sslmode = "require"
 	}
-	dsn := fmt.Sprintf(
+	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
 		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort, sslmode,
 	)
+}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func Connect(cfg *config.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(DSN(cfg)), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
@@ -32,10 +35,21 @@ func Migrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&models.User{},
 		&models.UserProfile{},
+		&models.SavedAddress{},
+		&models.ProfilePreset{},
 		&models.Group{},
 		&models.Member{},
 		&models.Exclusion{},
 		&models.Assignment{},
 		&models.Message{},
+		&models.MessageEdit{},
+		&models.ChatTicket{},
+		&models.ExchangeRate{},
+		&models.WishlistItem{},
+		&models.WishlistReservation{},
+		&models.LinkPreview{},
+		&models.GiftShipment{},
+		&models.ThankYouNote{},
+		&models.SantaGuess{},
 	)
 }
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"secret-santa/internal/address"
+)
+
+// addressFields - адресные поля запроса профиля (общие для ProfileRequest и ParticipantProfileRequest)
+type addressFields struct {
+	Line1, Line2, City, Region, PostalCode, Country **string
+	Line1En, Line2En, CityEn, RegionEn              **string
+}
+
+// localAddress - местная часть сохраненного адреса, по ней Normalize узнает автозаполненные английские поля
+func localAddress(line1, line2, city, region *string) address.Address {
+	get := func(p *string) string {
+		if p == nil {
+			return ""
+		}
+		return *p
+	}
+	return address.Address{Line1: get(line1), Line2: get(line2), City: get(city), Region: get(region)}
+}
+
+// normalizeAddressFields проверяет адрес по правилам страны, приводит индекс к ее формату
+// и заполняет английские поля транслитерацией (prev - сохраненный до изменения адрес)
+func normalizeAddressFields(f addressFields, prev address.Address) error {
+	get := func(p **string) string {
+		if *p == nil {
+			return ""
+		}
+		return **p
+	}
+
+	a := address.Address{
+		Line1:      get(f.Line1),
+		Line2:      get(f.Line2),
+		City:       get(f.City),
+		Region:     get(f.Region),
+		PostalCode: get(f.PostalCode),
+		Country:    get(f.Country),
+		Line1En:    get(f.Line1En),
+		Line2En:    get(f.Line2En),
+		CityEn:     get(f.CityEn),
+		RegionEn:   get(f.RegionEn),
+	}
+
+	if err := address.Validate(a); err != nil {
+		return err
+	}
+	address.Normalize(&a, prev)
+
+	set := func(p **string, value string) {
+		if value != get(p) {
+			*p = &value
+		}
+	}
+	set(f.PostalCode, a.PostalCode)
+	set(f.Country, a.Country)
+	set(f.Line1En, a.Line1En)
+	set(f.Line2En, a.Line2En)
+	set(f.CityEn, a.CityEn)
+	set(f.RegionEn, a.RegionEn)
+	return nil
+}
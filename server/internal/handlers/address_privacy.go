@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"secret-santa/internal/models"
+	"secret-santa/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// MaxAddressRevealDays - максимальный срок "открыть адрес за N дней до события"
+const MaxAddressRevealDays = 60
+
+// OfficeAddressRequest - адрес офиса для самовывоза
+type OfficeAddressRequest struct {
+	OfficeAddress string `json:"officeAddress"` // Пустая строка - убрать адрес
+}
+
+// UpdateOfficeAddress - организатор задает адрес офиса, куда можно отправлять подарки
+func (h *Handler) UpdateOfficeAddress(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var req OfficeAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var group models.Group
+	if err := h.DB.First(&group, rid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	if group.OwnerID != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only raffle owner can set office address"})
+		return
+	}
+
+	address, err := normalizeOfficeAddress(req.OfficeAddress)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Нельзя убрать офис, пока кто-то выбрал доставку туда
+	if address == nil {
+		var count int64
+		h.DB.Model(&models.Member{}).
+			Where("group_id = ? AND address_privacy = ?", rid, models.AddressPrivacyOffice).
+			Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Some participants ship to the office"})
+			return
+		}
+	}
+
+	group.OfficeAddress = address
+	if err := h.DB.Model(&group).Update("office_address", address).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update office address"})
+		return
+	}
+
+	h.DB.Preload("Members").Preload("Members.User").Preload("Members.WishlistItems").First(&group, rid)
+	c.JSON(http.StatusOK, h.raffleToResponse(group, uid, h.loadExchangeRates()))
+}
+
+// normalizeOfficeAddress очищает адрес офиса (nil - адрес не задан)
+func normalizeOfficeAddress(value string) (*string, error) {
+	address := validator.SanitizeString(value)
+	if address == "" {
+		return nil, nil
+	}
+	if err := validator.ValidateProfileField("officeAddress", address, validator.MaxAddressLength*2); err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// validateAddressPrivacy проверяет выбранный участником вариант приватности адреса
+func validateAddressPrivacy(mode string, revealDays *int, group models.Group) error {
+	switch mode {
+	case models.AddressPrivacyFull, models.AddressPrivacyHandDelivery:
+		return nil
+	case models.AddressPrivacyOffice:
+		if group.OfficeAddress == nil {
+			return errors.New("organizer has not set an office address")
+		}
+		return nil
+	case models.AddressPrivacyDelayed:
+		if group.EventDate == nil {
+			return errors.New("raffle has no event date")
+		}
+		if revealDays == nil || *revealDays < 1 || *revealDays > MaxAddressRevealDays {
+			return errors.New("address_reveal_days must be between 1 and 60")
+		}
+		return nil
+	}
+	return errors.New("invalid address_privacy")
+}
+
+// addressAvailableAt - с какого момента дарителю доступен домашний адрес скрытого получателя (nil - никогда)
+func addressAvailableAt(m models.Member, group models.Group) *time.Time {
+	if m.AddressPrivacy != models.AddressPrivacyDelayed || group.EventDate == nil || m.AddressRevealDays == nil {
+		return nil
+	}
+	at := group.EventDate.AddDate(0, 0, -*m.AddressRevealDays)
+	return &at
+}
+
+// applyAddressPrivacy скрывает адрес и телефон получателя согласно его настройкам.
+// Для доставки в офис вместо домашнего адреса подставляется адрес организатора.
+func applyAddressPrivacy(r *GifteeResponse, giftee models.Member, group models.Group, now time.Time) {
+	mode := giftee.AddressPrivacy
+	if mode == "" {
+		mode = models.AddressPrivacyFull
+	}
+	r.AddressPrivacy = mode
+	if mode == models.AddressPrivacyFull {
+		return
+	}
+
+	availableAt := addressAvailableAt(giftee, group)
+	if availableAt != nil && !availableAt.After(now) {
+		return
+	}
+	if availableAt != nil {
+		formatted := availableAt.In(raffleLocation(group)).Format(time.RFC3339)
+		r.AddressAvailableAt = &formatted
+	}
+
+	r.AddressHidden = true
+	r.Phone = nil
+	r.AddressLine1, r.AddressLine2 = nil, nil
+	r.City, r.Region, r.PostalCode = nil, nil, nil
+	r.AddressLine1En, r.AddressLine2En = nil, nil
+	r.CityEn, r.RegionEn = nil, nil
+
+	// Страну оставляем: она нужна для бюджета и выбора подарка
+	if mode == models.AddressPrivacyOffice && group.OfficeAddress != nil {
+		r.OfficeAddress = group.OfficeAddress
+	}
+}
+
+// addressPrivacyMode приводит значение из запроса к каноничному виду
+func addressPrivacyMode(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
@@ -0,0 +1,531 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"secret-santa/internal/models"
+	"secret-santa/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Ограничения адресной книги и пресетов
+const (
+	MaxSavedAddresses = 20
+	MaxProfilePresets = 20
+)
+
+var (
+	errSavedAddressNotFound  = errors.New("address not found")
+	errProfilePresetNotFound = errors.New("profile preset not found")
+)
+
+// SavedAddressRequest - создание/обновление адреса в адресной книге
+type SavedAddressRequest struct {
+	Label string  `json:"label" binding:"required"`
+	Phone *string `json:"phone"`
+
+	// Адрес на местном языке
+	AddressLine1 *string `json:"address_line1"`
+	AddressLine2 *string `json:"address_line2"`
+	City         *string `json:"city"`
+	Region       *string `json:"region"`
+	PostalCode   *string `json:"postal_code"`
+	Country      *string `json:"country"`
+
+	// Адрес на английском
+	AddressLine1En *string `json:"address_line1_en"`
+	AddressLine2En *string `json:"address_line2_en"`
+	CityEn         *string `json:"city_en"`
+	RegionEn       *string `json:"region_en"`
+}
+
+// SavedAddressResponse - адрес из адресной книги
+type SavedAddressResponse struct {
+	models.SavedAddress
+	UsedInRaffles  int  `json:"used_in_raffles"`           // В скольких розыгрышах выбран этот адрес
+	UpdatedRaffles *int `json:"updated_raffles,omitempty"` // Сколько активных розыгрышей обновлено после правки
+}
+
+// ProfilePresetRequest - создание/обновление пресета профиля
+type ProfilePresetRequest struct {
+	Name         string  `json:"name" binding:"required"`
+	AddressID    *string `json:"address_id"` // Адрес из адресной книги (пусто - без адреса)
+	About        *string `json:"about"`
+	Wishlist     *string `json:"wishlist"`
+	AntiWishlist *string `json:"anti_wishlist"`
+	Timezone     *string `json:"timezone"`
+}
+
+// GetSavedAddresses - адресная книга текущего пользователя
+func (h *Handler) GetSavedAddresses(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+
+	var addresses []models.SavedAddress
+	if err := h.DB.Where("user_id = ?", uid).Order("created_at ASC").Find(&addresses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch addresses"})
+		return
+	}
+
+	usage := h.savedAddressUsage(addresses)
+	response := make([]SavedAddressResponse, len(addresses))
+	for i, a := range addresses {
+		response[i] = SavedAddressResponse{SavedAddress: a, UsedInRaffles: usage[a.ID]}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateSavedAddress - добавить адрес в адресную книгу
+func (h *Handler) CreateSavedAddress(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+
+	var req SavedAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	h.DB.Model(&models.SavedAddress{}).Where("user_id = ?", uid).Count(&count)
+	if count >= MaxSavedAddresses {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many saved addresses"})
+		return
+	}
+
+	saved := models.SavedAddress{UserID: uid}
+	if err := fillSavedAddress(&saved, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+		return
+	}
+
+	if err := h.DB.Create(&saved).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save address"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, SavedAddressResponse{SavedAddress: saved})
+}
+
+// UpdateSavedAddress - изменить адрес; правка переносится во все активные розыгрыши, где он выбран
+func (h *Handler) UpdateSavedAddress(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+
+	saved, err := h.findSavedAddress(uid, c.Param("addressId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+
+	var req SavedAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := fillSavedAddress(&saved, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+		return
+	}
+
+	if err := h.DB.Save(&saved).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update address"})
+		return
+	}
+
+	updated := h.applySavedAddressToRaffles(saved)
+	c.JSON(http.StatusOK, SavedAddressResponse{
+		SavedAddress:   saved,
+		UsedInRaffles:  h.savedAddressUsage([]models.SavedAddress{saved})[saved.ID],
+		UpdatedRaffles: &updated,
+	})
+}
+
+// DeleteSavedAddress - удалить адрес из адресной книги.
+// Розыгрыши, где он выбран, сохраняют копию адреса, но больше не обновляются из книги.
+func (h *Handler) DeleteSavedAddress(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+
+	saved, err := h.findSavedAddress(uid, c.Param("addressId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var members []models.Member
+		if err := tx.Where("address_id = ?", saved.ID).Find(&members).Error; err != nil {
+			return err
+		}
+		for i := range members {
+			m := &members[i]
+			m.AddressID = nil
+			// Копия адреса остается в розыгрыше: основной профиль ее не перезаписывает
+			for _, f := range profileFields {
+				if f.Address && !containsString(m.OverriddenFields, f.Name) {
+					m.OverriddenFields = append(m.OverriddenFields, f.Name)
+				}
+			}
+			if err := tx.Model(m).Select("address_id", "overridden_fields").Updates(m).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.ProfilePreset{}).Where("address_id = ?", saved.ID).Update("address_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&saved).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Address deleted"})
+}
+
+// GetProfilePresets - пресеты профиля текущего пользователя
+func (h *Handler) GetProfilePresets(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+
+	var presets []models.ProfilePreset
+	if err := h.DB.Where("user_id = ?", uid).Order("created_at ASC").Find(&presets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch presets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, presets)
+}
+
+// CreateProfilePreset - создать пресет профиля
+func (h *Handler) CreateProfilePreset(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+
+	var req ProfilePresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	h.DB.Model(&models.ProfilePreset{}).Where("user_id = ?", uid).Count(&count)
+	if count >= MaxProfilePresets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many profile presets"})
+		return
+	}
+
+	preset := models.ProfilePreset{UserID: uid}
+	if !h.fillProfilePreset(c, &preset, req) {
+		return
+	}
+
+	if err := h.DB.Create(&preset).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preset"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, preset)
+}
+
+// UpdateProfilePreset - изменить пресет профиля (на розыгрыши, где он уже применен, не влияет)
+func (h *Handler) UpdateProfilePreset(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+
+	preset, err := h.findProfilePreset(uid, c.Param("presetId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile preset not found"})
+		return
+	}
+
+	var req ProfilePresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.fillProfilePreset(c, &preset, req) {
+		return
+	}
+
+	if err := h.DB.Save(&preset).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preset)
+}
+
+// DeleteProfilePreset - удалить пресет профиля
+func (h *Handler) DeleteProfilePreset(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+
+	result := h.DB.Where("id = ? AND user_id = ?", c.Param("presetId"), uid).Delete(&models.ProfilePreset{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete preset"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile preset not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Preset deleted"})
+}
+
+// fillSavedAddress проверяет и санитизирует запрос и переносит его в запись адресной книги
+func fillSavedAddress(saved *models.SavedAddress, req SavedAddressRequest) error {
+	label := validator.SanitizeString(req.Label)
+	if label == "" {
+		return errors.New("label is required")
+	}
+	if len(label) > validator.MaxNameLength {
+		return errors.New("label is too long")
+	}
+
+	prev := localAddress(saved.AddressLine1, saved.AddressLine2, saved.City, saved.Region)
+	saved.Label = label
+	saved.Phone = req.Phone
+	saved.AddressLine1 = req.AddressLine1
+	saved.AddressLine2 = req.AddressLine2
+	saved.City = req.City
+	saved.Region = req.Region
+	saved.PostalCode = req.PostalCode
+	saved.Country = req.Country
+	saved.AddressLine1En = req.AddressLine1En
+	saved.AddressLine2En = req.AddressLine2En
+	saved.CityEn = req.CityEn
+	saved.RegionEn = req.RegionEn
+
+	// Те же проверки, что и для адреса в основном профиле
+	data := make(map[string]string)
+	for _, f := range profileFields {
+		if f.saved == nil {
+			continue
+		}
+		if p := f.saved(saved); *p != nil {
+			data[f.Name] = **p
+		}
+	}
+	if err := validator.ValidateProfileData(data); err != nil {
+		return err
+	}
+
+	for _, f := range profileFields {
+		if f.saved == nil {
+			continue
+		}
+		if p := f.saved(saved); *p != nil {
+			sanitized := validator.SanitizeString(**p)
+			*p = &sanitized
+		}
+	}
+
+	return normalizeAddressFields(addressFields{
+		Line1: &saved.AddressLine1, Line2: &saved.AddressLine2, City: &saved.City, Region: &saved.Region,
+		PostalCode: &saved.PostalCode, Country: &saved.Country,
+		Line1En: &saved.AddressLine1En, Line2En: &saved.AddressLine2En, CityEn: &saved.CityEn, RegionEn: &saved.RegionEn,
+	}, prev)
+}
+
+// fillProfilePreset проверяет запрос и переносит его в пресет; при ошибке отвечает клиенту сам
+func (h *Handler) fillProfilePreset(c *gin.Context, preset *models.ProfilePreset, req ProfilePresetRequest) bool {
+	name := validator.SanitizeString(req.Name)
+	if name == "" || len(name) > validator.MaxNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid preset name"})
+		return false
+	}
+
+	data := make(map[string]string)
+	for key, value := range map[string]*string{
+		"about":         req.About,
+		"wishlist":      req.Wishlist,
+		"anti_wishlist": req.AntiWishlist,
+		"timezone":      req.Timezone,
+	} {
+		if value != nil {
+			data[key] = *value
+		}
+	}
+	if err := validator.ValidateProfileData(data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+		return false
+	}
+
+	preset.AddressID = nil
+	if req.AddressID != nil && *req.AddressID != "" {
+		saved, err := h.findSavedAddress(preset.UserID, *req.AddressID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Address not found"})
+			return false
+		}
+		preset.AddressID = &saved.ID
+	}
+
+	sanitize := func(s *string) *string {
+		if s == nil {
+			return nil
+		}
+		sanitized := validator.SanitizeString(*s)
+		return &sanitized
+	}
+
+	preset.Name = name
+	preset.About = sanitize(req.About)
+	preset.Wishlist = sanitize(req.Wishlist)
+	preset.AntiWishlist = sanitize(req.AntiWishlist)
+	preset.Timezone = sanitize(req.Timezone)
+	return true
+}
+
+// findSavedAddress находит адрес в адресной книге пользователя
+func (h *Handler) findSavedAddress(uid uuid.UUID, rawID string) (models.SavedAddress, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return models.SavedAddress{}, errSavedAddressNotFound
+	}
+
+	var saved models.SavedAddress
+	if err := h.DB.Where("id = ? AND user_id = ?", id, uid).First(&saved).Error; err != nil {
+		return models.SavedAddress{}, errSavedAddressNotFound
+	}
+	return saved, nil
+}
+
+// findProfilePreset находит пресет профиля пользователя
+func (h *Handler) findProfilePreset(uid uuid.UUID, rawID string) (models.ProfilePreset, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return models.ProfilePreset{}, errProfilePresetNotFound
+	}
+
+	var preset models.ProfilePreset
+	if err := h.DB.Where("id = ? AND user_id = ?", id, uid).First(&preset).Error; err != nil {
+		return models.ProfilePreset{}, errProfilePresetNotFound
+	}
+	return preset, nil
+}
+
+// savedAddressUsage считает, в скольких розыгрышах выбран каждый адрес
+func (h *Handler) savedAddressUsage(addresses []models.SavedAddress) map[uuid.UUID]int {
+	usage := make(map[uuid.UUID]int, len(addresses))
+	if len(addresses) == 0 {
+		return usage
+	}
+
+	ids := make([]uuid.UUID, len(addresses))
+	for i, a := range addresses {
+		ids[i] = a.ID
+	}
+
+	var rows []struct {
+		AddressID uuid.UUID
+		Count     int
+	}
+	h.DB.Model(&models.Member{}).
+		Select("address_id, COUNT(*) AS count").
+		Where("address_id IN ?", ids).
+		Group("address_id").
+		Scan(&rows)
+	for _, r := range rows {
+		usage[r.AddressID] = r.Count
+	}
+	return usage
+}
+
+// applySavedAddress копирует адрес из адресной книги в участника и привязывает его к записи книги.
+// Телефон переносится, только если он указан в адресе. Возвращает измененные поля.
+func applySavedAddress(m *models.Member, saved *models.SavedAddress) []string {
+	m.AddressID = &saved.ID
+
+	var changed []string
+	for _, f := range savedAddressFields(saved) {
+		value := *f.saved(saved)
+		if equalStringPtr(*f.member(m), value) {
+			continue
+		}
+		*f.member(m) = value
+		changed = append(changed, f.Name)
+	}
+	return changed
+}
+
+// savedAddressFields - поля участника, которые заполняются из записи адресной книги
+func savedAddressFields(saved *models.SavedAddress) []profileField {
+	var fields []profileField
+	for _, f := range profileFields {
+		if f.saved == nil {
+			continue
+		}
+		if f.Name == "phone" && *f.saved(saved) == nil {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// applyProfilePreset заполняет участника данными пресета.
+// Пустые поля пресета остаются как есть; заполненные отмечаются как измененные в розыгрыше.
+func applyProfilePreset(m *models.Member, preset *models.ProfilePreset) {
+	fields := []struct {
+		name   string
+		value  *string
+		target **string
+	}{
+		{"about", preset.About, &m.About},
+		{"wishlist", preset.Wishlist, &m.Wishlist},
+		{"anti_wishlist", preset.AntiWishlist, &m.AntiWishlist},
+		{"timezone", preset.Timezone, &m.Timezone},
+	}
+
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		*f.target = f.value
+		if !containsString(m.OverriddenFields, f.name) {
+			m.OverriddenFields = append(m.OverriddenFields, f.name)
+		}
+	}
+}
+
+// applySavedAddressToRaffles переносит правку адреса во все активные розыгрыши, где он выбран.
+// Возвращает число обновленных розыгрышей.
+func (h *Handler) applySavedAddressToRaffles(saved models.SavedAddress) int {
+	var members []models.Member
+	if err := h.DB.Preload("Group").Where("address_id = ? AND user_id = ?", saved.ID, saved.UserID).Find(&members).Error; err != nil {
+		log.Printf("Failed to load raffles for address %s: %v", saved.ID, err)
+		return 0
+	}
+
+	now := time.Now()
+	updated := 0
+	for i := range members {
+		m := &members[i]
+		if !isRaffleActive(m.Group, now) {
+			continue
+		}
+
+		changed := applySavedAddress(m, &saved)
+		if len(changed) == 0 {
+			continue
+		}
+
+		// Только поля адреса (имя поля совпадает с колонкой): остальное могло измениться параллельно
+		if err := h.DB.Model(m).Select(changed).Updates(m).Error; err != nil {
+			log.Printf("Failed to sync address to member %s: %v", m.ID, err)
+			continue
+		}
+		updated++
+
+		h.notifyAddressChanged(m, changed)
+	}
+
+	return updated
+}
@@ -4,44 +4,13 @@ import (
 	"errors"
 	"log"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 
-	"secret-santa/internal/crypto"
 	"secret-santa/internal/models"
 )
 
-// validateToken проверяет JWT токен и возвращает user_id
-func (h *Handler) validateToken(tokenString string) (uuid.UUID, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(h.cfg.JWTSecret), nil
-	})
-
-	if err != nil || !token.Valid {
-		return uuid.Nil, errors.New("invalid token")
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return uuid.Nil, errors.New("invalid token claims")
-	}
-
-	userIDStr, ok := claims["sub"].(string)
-	if !ok {
-		return uuid.Nil, errors.New("invalid user ID in token")
-	}
-
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		return uuid.Nil, errors.New("invalid user ID format")
-	}
-
-	return userID, nil
-}
-
 // HandleWebSocket обрабатывает WebSocket подключения для чата
 func (h *Handler) HandleWebSocket(c *gin.Context) {
 	groupIDStr := c.Param("id")
@@ -51,17 +20,20 @@ func (h *Handler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// Получаем токен из query параметра (WebSocket не поддерживает кастомные заголовки)
-	token := c.Query("token")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token required"})
+	// WebSocket не поддерживает кастомные заголовки, поэтому вместо JWT в query передается
+	// одноразовый билет из CreateChatTicket: он живет секунды и бесполезен в логах прокси
+	ticket := c.Query("ticket")
+	if ticket == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Ticket required"})
 		return
 	}
 
-	// Валидируем токен и получаем user_id
-	userID, err := h.validateToken(token)
+	userID, err := h.consumeChatTicket(groupID, ticket)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		if !errors.Is(err, errInvalidTicket) {
+			log.Printf("Failed to consume chat ticket: %v", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired ticket"})
 		return
 	}
 
@@ -84,31 +56,37 @@ func (h *Handler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	// Собеседники по паре: кому я дарю и кто дарит мне
+	var gifteeID, santaID uuid.UUID
+	if member.GifteeID != nil {
+		gifteeID = *member.GifteeID
+	}
+	var santa models.Member
+	if err := h.DB.First(&santa, "group_id = ? AND giftee_id = ?", groupID, member.ID).Error; err == nil {
+		santaID = santa.ID
+	}
+
 	// Апгрейдим HTTP соединение до WebSocket
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		return
 	}
 
-	// Создаем клиента
-	client := &Client{
-		hub:      h.Hub,
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		userID:   userID,
-		memberID: member.ID,
-		groupID:  groupID,
-	}
-
 	// Регистрируем клиента в Hub
-	h.Hub.register <- client
+	h.Hub.Attach(conn, ClientInfo{
+		UserID:   userID,
+		GroupID:  groupID,
+		MemberID: member.ID,
+		SantaID:  santaID,
+		GifteeID: gifteeID,
+	})
 
-	// Запускаем горутины для чтения и записи
-	go client.writePump()
-	go client.readPump()
+	// Начальные счетчики непрочитанных для бейджа
+	h.Hub.pushUnread(groupID, member.ID)
 }
 
-// GetChatWithGiftee возвращает историю сообщений с получателем (я - даритель)
+// GetChatWithGiftee возвращает страницу истории сообщений с получателем (я - даритель).
+// ?before=<id> - более ранние сообщения, ?after=<id> - более поздние, ?limit= - размер страницы.
 func (h *Handler) GetChatWithGiftee(c *gin.Context) {
 	groupIDStr := c.Param("id")
 	groupID, err := uuid.Parse(groupIDStr)
@@ -132,48 +110,41 @@ func (h *Handler) GetChatWithGiftee(c *gin.Context) {
 		return
 	}
 
-	// Получаем все сообщения между мной (santa) и моим получателем (giftee)
-	var messages []models.Message
-	if err := h.DB.Where("group_id = ? AND santa_id = ? AND giftee_id = ?",
-		groupID, member.ID, *member.GifteeID).
-		Order("created_at ASC").
-		Find(&messages).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+	// Страница истории между мной (santa) и моим получателем (giftee).
+	// Прочитанными сообщения отмечаются отдельно (MarkGifteeChatRead или событие read)
+	page, err := h.parseChatPage(c, groupID, member.ID, *member.GifteeID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Помечаем все непрочитанные сообщения от получателя как прочитанные
-	now := time.Now()
-	h.DB.Model(&models.Message{}).
-		Where("group_id = ? AND santa_id = ? AND giftee_id = ? AND from_santa = false AND read_at IS NULL",
-			groupID, member.ID, *member.GifteeID).
-		Update("read_at", now)
-
-	// Расшифровываем и преобразуем в DTO
-	chatMessages := make([]gin.H, len(messages))
-	for i, msg := range messages {
-		// Расшифровываем содержимое
-		decryptedContent, err := crypto.Decrypt(msg.Content, h.encryptionKey)
-		if err != nil {
-			log.Printf("Failed to decrypt message %s: %v", msg.ID, err)
-			decryptedContent = "[Encrypted message]" // Fallback на случай ошибки
-		}
-		msg.Content = decryptedContent
-		chatMessages[i] = gin.H{
-			"id":         msg.ID,
-			"santa_id":   msg.SantaID,
-			"giftee_id":  msg.GifteeID,
-			"from_santa": msg.FromSanta,
-			"content":    msg.Content,
-			"read_at":    msg.ReadAt,
-			"created_at": msg.CreatedAt,
-		}
+	messages, hasMore, err := h.loadChatPage(groupID, member.ID, *member.GifteeID, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
 	}
 
-	c.JSON(http.StatusOK, chatMessages)
+	c.JSON(http.StatusOK, ChatWithGifteeResponse{
+		Messages: h.messagesToResponse(messages),
+		HasMore:  hasMore,
+	})
 }
 
-// GetChatWithSanta возвращает историю сообщений с дарителем (я - получатель)
+// ChatWithGifteeResponse - страница истории чата с получателем
+type ChatWithGifteeResponse struct {
+	Messages []gin.H `json:"messages"`
+	HasMore  bool    `json:"has_more"` // Есть еще сообщения в направлении листания
+}
+
+// ChatWithSantaResponse - история чата с дарителем
+type ChatWithSantaResponse struct {
+	Santa    *RevealedSantaResponse `json:"santa"` // nil до раскрытия или если даритель остался анонимным
+	Messages []gin.H                `json:"messages"`
+	HasMore  bool                   `json:"has_more"` // Есть еще сообщения в направлении листания
+}
+
+// GetChatWithSanta возвращает страницу истории сообщений с дарителем (я - получатель), параметры как у GetChatWithGiftee.
+// После раскрытия розыгрыша в ответе есть имя и аватар дарителя (если он не остался анонимным).
 func (h *Handler) GetChatWithSanta(c *gin.Context) {
 	groupIDStr := c.Param("id")
 	groupID, err := uuid.Parse(groupIDStr)
@@ -198,45 +169,24 @@ func (h *Handler) GetChatWithSanta(c *gin.Context) {
 		return
 	}
 
-	// Получаем все сообщения между моим дарителем и мной
-	var messages []models.Message
-	if err := h.DB.Where("group_id = ? AND santa_id = ? AND giftee_id = ?",
-		groupID, santa.ID, member.ID).
-		Order("created_at ASC").
-		Find(&messages).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+	// Страница истории между моим дарителем и мной
+	page, err := h.parseChatPage(c, groupID, santa.ID, member.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Помечаем все непрочитанные сообщения от дарителя как прочитанные
-	now := time.Now()
-	h.DB.Model(&models.Message{}).
-		Where("group_id = ? AND santa_id = ? AND giftee_id = ? AND from_santa = true AND read_at IS NULL",
-			groupID, santa.ID, member.ID).
-		Update("read_at", now)
-
-	// Расшифровываем и преобразуем в DTO
-	chatMessages := make([]gin.H, len(messages))
-	for i, msg := range messages {
-		// Расшифровываем содержимое
-		decryptedContent, err := crypto.Decrypt(msg.Content, h.encryptionKey)
-		if err != nil {
-			log.Printf("Failed to decrypt message %s: %v", msg.ID, err)
-			decryptedContent = "[Encrypted message]" // Fallback на случай ошибки
-		}
-		msg.Content = decryptedContent
-		chatMessages[i] = gin.H{
-			"id":         msg.ID,
-			"santa_id":   msg.SantaID,
-			"giftee_id":  msg.GifteeID,
-			"from_santa": msg.FromSanta,
-			"content":    msg.Content,
-			"read_at":    msg.ReadAt,
-			"created_at": msg.CreatedAt,
-		}
+	messages, hasMore, err := h.loadChatPage(groupID, santa.ID, member.ID, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
 	}
 
-	c.JSON(http.StatusOK, chatMessages)
+	c.JSON(http.StatusOK, ChatWithSantaResponse{
+		Santa:    h.revealedSanta(santa),
+		Messages: h.messagesToResponse(messages),
+		HasMore:  hasMore,
+	})
 }
 
 // GetUnreadCount возвращает количество непрочитанных сообщений для участника
@@ -257,25 +207,8 @@ func (h *Handler) GetUnreadCount(c *gin.Context) {
 		return
 	}
 
-	var unreadFromGiftee int64
-	var unreadFromSanta int64
-
-	// Считаем непрочитанные от получателя (если я - даритель)
-	if member.GifteeID != nil {
-		h.DB.Model(&models.Message{}).
-			Where("group_id = ? AND santa_id = ? AND giftee_id = ? AND from_santa = false AND read_at IS NULL",
-				groupID, member.ID, *member.GifteeID).
-			Count(&unreadFromGiftee)
-	}
-
-	// Считаем непрочитанные от дарителя (если я - получатель)
-	var santa models.Member
-	if err := h.DB.First(&santa, "group_id = ? AND giftee_id = ?", groupID, member.ID).Error; err == nil {
-		h.DB.Model(&models.Message{}).
-			Where("group_id = ? AND santa_id = ? AND giftee_id = ? AND from_santa = true AND read_at IS NULL",
-				groupID, santa.ID, member.ID).
-			Count(&unreadFromSanta)
-	}
+	// Непрочитанные от получателя (я - даритель) и от дарителя (я - получатель)
+	unreadFromGiftee, unreadFromSanta := h.Hub.unreadCounts(groupID, member.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"unread_from_giftee": unreadFromGiftee,
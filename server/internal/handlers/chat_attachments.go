@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"secret-santa/internal/crypto"
+	"secret-santa/internal/models"
+	"secret-santa/internal/validator"
+)
+
+// Вложения анонимного чата
+const (
+	MaxChatAttachmentSize = 10 * 1024 * 1024 // 10MB
+	ChatAttachmentURLTTL  = 5 * time.Minute  // Сколько живет ссылка на скачивание
+
+	// chatAttachmentPrefix - закрытый префикс бакета (в отличие от avatars/ не читается по прямой ссылке)
+	chatAttachmentPrefix = "private/chat"
+)
+
+// allowedChatAttachmentTypes - какие файлы можно прислать в чат и с каким расширением они хранятся
+// (SVG и HTML исключены: могут содержать скрипты). Тип определяется по содержимому файла.
+var allowedChatAttachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// ChatAttachment - вложение сообщения в DTO (без ссылки: ее выдает GetChatAttachmentURL)
+type ChatAttachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// ChatAttachmentURLResponse - временная ссылка на вложение
+type ChatAttachmentURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SendChatAttachment - отправить в чат пары фото или файл (multipart: file, role, content - необязательная подпись).
+// role как у WebSocket-сообщений: "santa" - пишу получателю, "giftee" - пишу дарителю.
+func (h *Handler) SendChatAttachment(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var member models.Member
+	if err := h.DB.First(&member, "group_id = ? AND user_id = ?", groupID, c.GetString("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
+		return
+	}
+
+	// Пара по роли отправителя
+	var santaID, gifteeID uuid.UUID
+	fromSanta := c.PostForm("role") == "santa"
+	if fromSanta {
+		if member.GifteeID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Draw has not been performed yet"})
+			return
+		}
+		santaID, gifteeID = member.ID, *member.GifteeID
+	} else {
+		var santa models.Member
+		if err := h.DB.First(&santa, "group_id = ? AND giftee_id = ?", groupID, member.ID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Draw has not been performed yet or you don't have a santa"})
+			return
+		}
+		santaID, gifteeID = santa.ID, member.ID
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+
+	if file.Size > MaxChatAttachmentSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is too large (max 10MB)"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+		return
+	}
+	defer src.Close()
+
+	// Content-Type и имя файла задает клиент - тип и расширение берем из содержимого
+	contentType, err := sniffChatAttachment(src)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported file type"})
+		return
+	}
+
+	caption := validator.SanitizeString(c.PostForm("content"))
+	if caption != "" {
+		if err := validator.ValidateMessage(caption); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message: " + err.Error()})
+			return
+		}
+	}
+
+	// Имя файла тоже может выдать отправителя - хранится зашифрованным, как и текст
+	name := validator.SanitizeString(filepath.Base(file.Filename))
+	encryptedName, err := crypto.Encrypt(name, h.encryptionKey)
+	if err != nil {
+		log.Printf("Failed to encrypt attachment name: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send attachment"})
+		return
+	}
+	encryptedContent, err := crypto.Encrypt(caption, h.encryptionKey)
+	if err != nil {
+		log.Printf("Failed to encrypt message: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send attachment"})
+		return
+	}
+
+	key, err := h.storage.UploadPrivate(c.Request.Context(), chatAttachmentPrefix+"/"+groupID.String(), src,
+		"attachment"+allowedChatAttachmentTypes[contentType], contentType)
+	if err != nil {
+		log.Printf("Failed to upload chat attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file"})
+		return
+	}
+
+	size := file.Size
+	dbMessage := models.Message{
+		GroupID:        groupID,
+		SantaID:        santaID,
+		GifteeID:       gifteeID,
+		FromSanta:      fromSanta,
+		Content:        encryptedContent,
+		AttachmentKey:  &key,
+		AttachmentName: &encryptedName,
+		AttachmentType: &contentType,
+		AttachmentSize: &size,
+	}
+	if err := h.DB.Create(&dbMessage).Error; err != nil {
+		// Файл без сообщения никому не доступен - удаляем его сразу
+		if err := h.storage.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete orphaned chat attachment %s: %v", key, err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send attachment"})
+		return
+	}
+
+	chatMsg := h.Hub.chatMessage(&dbMessage, caption)
+	h.Hub.Broadcast(groupID, chatMsg)
+
+	// У собеседника выросло число непрочитанных
+	recipient := gifteeID
+	if !fromSanta {
+		recipient = santaID
+	}
+	h.Hub.pushUnread(groupID, recipient)
+
+	c.JSON(http.StatusCreated, chatMsg)
+}
+
+// GetChatAttachmentURL - временная ссылка на вложение сообщения (только для дарителя и получателя этой пары)
+func (h *Handler) GetChatAttachmentURL(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	messageID, err := uuid.Parse(c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var member models.Member
+	if err := h.DB.First(&member, "group_id = ? AND user_id = ?", groupID, c.GetString("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
+		return
+	}
+
+	// Сообщения чужих пар неотличимы от несуществующих
+	var msg models.Message
+	if err := h.DB.First(&msg, "id = ? AND group_id = ? AND (santa_id = ? OR giftee_id = ?)",
+		messageID, groupID, member.ID, member.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	attachment := chatAttachment(&msg, h.encryptionKey)
+	if attachment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	// Изображения открываются в браузере, остальные файлы скачиваются под исходным именем
+	downloadName := attachment.Name
+	if isChatImage(attachment.ContentType) {
+		downloadName = ""
+	}
+
+	url, err := h.storage.PresignGet(c.Request.Context(), *msg.AttachmentKey, ChatAttachmentURLTTL, downloadName)
+	if err != nil {
+		log.Printf("Failed to presign chat attachment %s: %v", msg.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get attachment"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, ChatAttachmentURLResponse{
+		URL:       url,
+		ExpiresAt: time.Now().Add(ChatAttachmentURLTTL),
+	})
+}
+
+// chatAttachment возвращает вложение сообщения с расшифрованным именем (nil - вложения нет)
+func chatAttachment(msg *models.Message, key []byte) *ChatAttachment {
+	if msg.AttachmentKey == nil {
+		return nil
+	}
+
+	attachment := &ChatAttachment{}
+	if msg.AttachmentType != nil {
+		attachment.ContentType = *msg.AttachmentType
+	}
+	if msg.AttachmentSize != nil {
+		attachment.Size = *msg.AttachmentSize
+	}
+	if msg.AttachmentName != nil {
+		name, err := crypto.Decrypt(*msg.AttachmentName, key)
+		if err != nil {
+			log.Printf("Failed to decrypt attachment name %s: %v", msg.ID, err)
+			name = "attachment" + filepath.Ext(*msg.AttachmentKey)
+		}
+		attachment.Name = name
+	}
+	return attachment
+}
+
+// sniffChatAttachment определяет тип файла по первым 512 байтам и возвращает читатель в начало файла.
+// Тип не из allowedChatAttachmentTypes - ошибка.
+func sniffChatAttachment(src io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return "", err
+	}
+	if _, ok := allowedChatAttachmentTypes[contentType]; !ok {
+		return "", fmt.Errorf("unsupported file type %s", contentType)
+	}
+	return contentType, nil
+}
+
+func isChatImage(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSniffChatAttachment(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // Пусто - файл должен быть отклонен
+	}{
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"jpeg", "\xff\xd8\xff\xe0\x00\x10JFIF\x00", "image/jpeg"},
+		{"gif", "GIF89a\x01\x00\x01\x00", "image/gif"},
+		{"webp", "RIFF\x24\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"pdf", "%PDF-1.7\n%\xe2\xe3\xcf\xd3\n", "application/pdf"},
+		{"text", "Привет, Санта! Список подарков внутри.", "text/plain"},
+		{"html", "<!DOCTYPE html><html><script>alert(1)</script></html>", ""},
+		{"svg", `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`, ""},
+		{"executable", "MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff", ""},
+		{"zip", "PK\x03\x04\x14\x00\x00\x00", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sniffChatAttachment(strings.NewReader(tt.data))
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("sniffChatAttachment() = %q, want rejection", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("sniffChatAttachment() = %q, %v, want %q", got, err, tt.want)
+			}
+			if _, ok := allowedChatAttachmentTypes[got]; !ok {
+				t.Errorf("%q has no storage extension", got)
+			}
+		})
+	}
+}
+
+func TestSniffChatAttachmentRewinds(t *testing.T) {
+	data := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 2048)...)
+	src := bytes.NewReader(data)
+
+	if _, err := sniffChatAttachment(src); err != nil {
+		t.Fatalf("sniffChatAttachment() error = %v", err)
+	}
+	// Загружается весь файл, а не то, что осталось после определения типа
+	rest, _ := io.ReadAll(src)
+	if !bytes.Equal(rest, data) {
+		t.Errorf("read %d bytes after sniffing, want %d", len(rest), len(data))
+	}
+}
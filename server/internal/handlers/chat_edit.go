@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"secret-santa/internal/crypto"
+	"secret-santa/internal/models"
+	"secret-santa/internal/validator"
+)
+
+// MessageEditWindow - сколько времени после отправки свое сообщение можно редактировать и удалять
+const MessageEditWindow = 15 * time.Minute
+
+var errMessageDeleted = errors.New("message has been deleted")
+
+// EditMessageRequest - новый текст сообщения (у сообщения с вложением - подпись, может быть пустой)
+type EditMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// MessageVersion - предыдущая версия сообщения из истории правок
+type MessageVersion struct {
+	Content    string    `json:"content"`
+	ReplacedAt time.Time `json:"replaced_at"` // Когда версия была заменена следующей
+}
+
+// EditChatMessage - исправить свое сообщение (в течение MessageEditWindow после отправки).
+// Прежний текст сохраняется в истории правок, собеседник получает событие edit.
+func (h *Handler) EditChatMessage(c *gin.Context) {
+	var req EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupID, msg, ok := h.findOwnMessage(c)
+	if !ok {
+		return
+	}
+	if !checkMessageEditable(c, msg) {
+		return
+	}
+
+	req.Content = validator.SanitizeString(req.Content)
+	if req.Content != "" || msg.AttachmentKey == nil {
+		if err := validator.ValidateMessage(req.Content); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message: " + err.Error()})
+			return
+		}
+	}
+
+	current, err := crypto.Decrypt(msg.Content, h.encryptionKey)
+	if err != nil {
+		log.Printf("Failed to decrypt message %s: %v", msg.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit message"})
+		return
+	}
+	if current == req.Content {
+		c.JSON(http.StatusOK, h.Hub.chatMessage(msg, current))
+		return
+	}
+
+	encryptedContent, err := crypto.Encrypt(req.Content, h.encryptionKey)
+	if err != nil {
+		log.Printf("Failed to encrypt message: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit message"})
+		return
+	}
+
+	now := time.Now()
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		current, err := lockMessage(tx, msg.ID)
+		if err != nil {
+			return err
+		}
+		// Прежний текст уходит в историю как есть (уже зашифрован)
+		if err := tx.Create(&models.MessageEdit{MessageID: msg.ID, Content: current.Content}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Message{}).
+			Where("id = ?", msg.ID).
+			Updates(map[string]interface{}{"content": encryptedContent, "edited_at": now}).Error
+	})
+	if errors.Is(err, errMessageDeleted) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message has been deleted"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit message"})
+		return
+	}
+
+	msg.Content = encryptedContent
+	msg.EditedAt = &now
+	chatMsg := h.Hub.chatMessage(msg, req.Content)
+	h.Hub.broadcastMessage(groupID, chatMsg, EventEdit)
+
+	c.JSON(http.StatusOK, chatMsg)
+}
+
+// DeleteChatMessage - удалить свое сообщение (в течение MessageEditWindow после отправки).
+// Текст, вложение и история правок удаляются безвозвратно, в чате остается заглушка.
+func (h *Handler) DeleteChatMessage(c *gin.Context) {
+	groupID, msg, ok := h.findOwnMessage(c)
+	if !ok {
+		return
+	}
+	if !checkMessageEditable(c, msg) {
+		return
+	}
+
+	now := time.Now()
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// После блокировки строки параллельная правка либо уже записала историю, либо дождется
+		// удаления и увидит его - ее версия не переживет удаление
+		if _, err := lockMessage(tx, msg.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Message{}).
+			Where("id = ?", msg.ID).
+			Updates(map[string]interface{}{
+				"content":         "",
+				"deleted_at":      now,
+				"attachment_key":  nil,
+				"attachment_name": nil,
+				"attachment_type": nil,
+				"attachment_size": nil,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Where("message_id = ?", msg.ID).Delete(&models.MessageEdit{}).Error
+	})
+	if errors.Is(err, errMessageDeleted) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message has been deleted"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete message"})
+		return
+	}
+
+	if msg.AttachmentKey != nil {
+		if err := h.storage.Delete(c.Request.Context(), *msg.AttachmentKey); err != nil {
+			log.Printf("Failed to delete chat attachment %s: %v", *msg.AttachmentKey, err)
+		}
+	}
+
+	msg.Content = ""
+	msg.DeletedAt = &now
+	msg.AttachmentKey, msg.AttachmentName, msg.AttachmentType, msg.AttachmentSize = nil, nil, nil, nil
+	h.Hub.broadcastMessage(groupID, h.Hub.chatMessage(msg, ""), EventDelete)
+
+	// Непрочитанное удаленное сообщение больше не считается у собеседника
+	if msg.ReadAt == nil {
+		recipient := msg.GifteeID
+		if !msg.FromSanta {
+			recipient = msg.SantaID
+		}
+		h.Hub.pushUnread(groupID, recipient)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message deleted"})
+}
+
+// GetChatMessageHistory - предыдущие версии своего сообщения (собеседнику история недоступна)
+func (h *Handler) GetChatMessageHistory(c *gin.Context) {
+	_, msg, ok := h.findOwnMessage(c)
+	if !ok {
+		return
+	}
+
+	// У удаленного сообщения истории нет
+	if msg.DeletedAt != nil {
+		c.JSON(http.StatusOK, []MessageVersion{})
+		return
+	}
+
+	var edits []models.MessageEdit
+	if err := h.DB.Where("message_id = ?", msg.ID).Order("created_at ASC").Find(&edits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch message history"})
+		return
+	}
+
+	versions := make([]MessageVersion, len(edits))
+	for i, edit := range edits {
+		content, err := crypto.Decrypt(edit.Content, h.encryptionKey)
+		if err != nil {
+			log.Printf("Failed to decrypt message edit %s: %v", edit.ID, err)
+			content = "[Encrypted message]"
+		}
+		versions[i] = MessageVersion{Content: content, ReplacedAt: edit.CreatedAt}
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// findOwnMessage находит сообщение :messageId розыгрыша :id, написанное текущим пользователем.
+// Чужие сообщения неотличимы от несуществующих.
+func (h *Handler) findOwnMessage(c *gin.Context) (uuid.UUID, *models.Message, bool) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return uuid.Nil, nil, false
+	}
+
+	messageID, err := uuid.Parse(c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return uuid.Nil, nil, false
+	}
+
+	var member models.Member
+	if err := h.DB.First(&member, "group_id = ? AND user_id = ?", groupID, c.GetString("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
+		return uuid.Nil, nil, false
+	}
+
+	var msg models.Message
+	if err := h.DB.First(&msg, "id = ? AND group_id = ?", messageID, groupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return uuid.Nil, nil, false
+	}
+
+	author := msg.GifteeID
+	if msg.FromSanta {
+		author = msg.SantaID
+	}
+	if author != member.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return uuid.Nil, nil, false
+	}
+
+	return groupID, &msg, true
+}
+
+// lockMessage блокирует строку сообщения до конца транзакции (SELECT ... FOR UPDATE):
+// правки и удаление одного сообщения выполняются по очереди
+func lockMessage(tx *gorm.DB, id uuid.UUID) (*models.Message, error) {
+	var msg models.Message
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&msg, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if msg.DeletedAt != nil {
+		return nil, errMessageDeleted // Удалено параллельным запросом
+	}
+	return &msg, nil
+}
+
+// checkMessageEditable проверяет, что сообщение еще можно править или удалить
+func checkMessageEditable(c *gin.Context, msg *models.Message) bool {
+	if msg.DeletedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message has been deleted"})
+		return false
+	}
+	if time.Since(msg.CreatedAt) > MessageEditWindow {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Message can no longer be changed"})
+		return false
+	}
+	return true
+}
+
+// chatMessage собирает DTO сообщения с уже расшифрованным текстом
+func (h *Hub) chatMessage(msg *models.Message, content string) *ChatMessage {
+	return &ChatMessage{
+		ID:        msg.ID,
+		SantaID:   msg.SantaID,
+		GifteeID:  msg.GifteeID,
+		FromSanta: msg.FromSanta,
+		Content:   content,
+		ReadAt:    msg.ReadAt,
+		EditedAt:  msg.EditedAt,
+		DeletedAt: msg.DeletedAt,
+		CreatedAt: msg.CreatedAt,
+
+		Attachment: chatAttachment(msg, h.encryptionKey),
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Типы событий WebSocket-протокола чата
+const (
+	EventMessage  = "message"  // Новое сообщение
+	EventTyping   = "typing"   // Собеседник печатает
+	EventPresence = "presence" // Собеседник в сети / не в сети
+	EventRead     = "read"     // Сообщения прочитаны
+	EventError    = "error"    // Ошибка обработки события клиента
+	EventAck      = "ack"      // Сообщение клиента сохранено
+	EventUnread   = "unread"   // Новые счетчики непрочитанных (только от сервера)
+	EventEdit     = "edit"     // Сообщение отредактировано (только от сервера, поля как у message)
+	EventDelete   = "delete"   // Сообщение удалено (только от сервера, поля как у message, content пустой)
+)
+
+// Чаты пары с точки зрения участника
+const (
+	ChatWithGiftee = "giftee" // Я даритель, пишу своему получателю
+	ChatWithSanta  = "santa"  // Я получатель, пишу своему дарителю
+)
+
+// IncomingEvent - событие от клиента.
+// Сообщения без type (старый формат {content, role}) считаются EventMessage.
+type IncomingEvent struct {
+	Type     string `json:"type"`
+	ClientID string `json:"client_id,omitempty"` // Идентификатор клиента, возвращается в ack/error
+	Role     string `json:"role"`                // "santa" - пишу получателю, "giftee" - пишу дарителю
+	Content  string `json:"content,omitempty"`   // Для EventMessage
+	Typing   bool   `json:"typing,omitempty"`    // Для EventTyping: начал (true) или перестал (false) печатать
+
+	MessageIDs []uuid.UUID `json:"message_ids,omitempty"` // Для EventRead: какие сообщения собеседника прочитаны
+}
+
+// MessageEvent - новое сообщение в чате пары (поля ChatMessage на верхнем уровне)
+type MessageEvent struct {
+	Type string `json:"type"` // "message"
+	*ChatMessage
+}
+
+// TypingEvent - собеседник печатает. Без ID участников: только в каком из двух чатов.
+type TypingEvent struct {
+	Type   string `json:"type"` // "typing"
+	Chat   string `json:"chat"` // ChatWithGiftee или ChatWithSanta с точки зрения получателя события
+	Typing bool   `json:"typing"`
+}
+
+// PresenceEvent - собеседник подключился или отключился
+type PresenceEvent struct {
+	Type   string `json:"type"` // "presence"
+	Chat   string `json:"chat"` // ChatWithGiftee или ChatWithSanta с точки зрения получателя события
+	Online bool   `json:"online"`
+}
+
+// AckEvent - сообщение клиента сохранено
+type AckEvent struct {
+	Type      string    `json:"type"` // "ack"
+	ClientID  string    `json:"client_id,omitempty"`
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ErrorEvent - событие клиента отклонено
+type ErrorEvent struct {
+	Type     string `json:"type"` // "error"
+	ClientID string `json:"client_id,omitempty"`
+	Error    string `json:"error"`
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"secret-santa/internal/crypto"
+	"secret-santa/internal/models"
+)
+
+// Размер страницы истории чата
+const (
+	DefaultChatPageSize = 50
+	MaxChatPageSize     = 200
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// chatPage - параметры страницы истории: сообщения до/после курсора (ID сообщения)
+type chatPage struct {
+	Before *models.Message
+	After  *models.Message
+	Limit  int
+}
+
+// MarkReadRequest - какие сообщения собеседника отметить прочитанными (не передано - все)
+type MarkReadRequest struct {
+	MessageIDs []uuid.UUID `json:"message_ids"`
+}
+
+// parseChatPage разбирает ?before=&after=&limit= для чата пары.
+// Курсор должен быть сообщением этой же пары.
+func (h *Handler) parseChatPage(c *gin.Context, groupID, santaID, gifteeID uuid.UUID) (chatPage, error) {
+	page := chatPage{Limit: DefaultChatPageSize}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return page, errors.New("invalid limit")
+		}
+		page.Limit = min(limit, MaxChatPageSize)
+	}
+
+	before, after := c.Query("before"), c.Query("after")
+	if before != "" && after != "" {
+		return page, errors.New("use either before or after")
+	}
+
+	cursor := func(raw string) (*models.Message, error) {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		var msg models.Message
+		if err := h.DB.Select("id", "created_at").
+			Where("id = ? AND group_id = ? AND santa_id = ? AND giftee_id = ?", id, groupID, santaID, gifteeID).
+			First(&msg).Error; err != nil {
+			return nil, errInvalidCursor
+		}
+		return &msg, nil
+	}
+
+	var err error
+	switch {
+	case before != "":
+		page.Before, err = cursor(before)
+	case after != "":
+		page.After, err = cursor(after)
+	}
+	return page, err
+}
+
+// loadChatPage загружает страницу истории пары в хронологическом порядке.
+// hasMore - есть ли еще сообщения в направлении листания (по умолчанию и для before - более старые).
+func (h *Handler) loadChatPage(groupID, santaID, gifteeID uuid.UUID, page chatPage) ([]models.Message, bool, error) {
+	// Индекс idx_chat_history (group_id, santa_id, giftee_id, created_at) покрывает фильтр и сортировку
+	query := h.DB.Where("group_id = ? AND santa_id = ? AND giftee_id = ?", groupID, santaID, gifteeID)
+
+	ascending := false
+	switch {
+	case page.Before != nil:
+		query = query.Where("(created_at, id) < (?, ?)", page.Before.CreatedAt, page.Before.ID)
+	case page.After != nil:
+		query = query.Where("(created_at, id) > (?, ?)", page.After.CreatedAt, page.After.ID)
+		ascending = true
+	}
+
+	order := "created_at DESC, id DESC"
+	if ascending {
+		order = "created_at ASC, id ASC"
+	}
+
+	var messages []models.Message
+	if err := query.Order(order).Limit(page.Limit + 1).Find(&messages).Error; err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(messages) > page.Limit
+	if hasMore {
+		messages = messages[:page.Limit]
+	}
+
+	if !ascending {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	return messages, hasMore, nil
+}
+
+// messagesToResponse расшифровывает сообщения и преобразует их в DTO
+func (h *Handler) messagesToResponse(messages []models.Message) []gin.H {
+	chatMessages := make([]gin.H, len(messages))
+	for i, msg := range messages {
+		// Расшифровываем содержимое (у удаленных сообщений текста нет)
+		var decryptedContent string
+		if msg.DeletedAt == nil {
+			var err error
+			decryptedContent, err = crypto.Decrypt(msg.Content, h.encryptionKey)
+			if err != nil {
+				log.Printf("Failed to decrypt message %s: %v", msg.ID, err)
+				decryptedContent = "[Encrypted message]" // Fallback на случай ошибки
+			}
+		}
+		chatMessages[i] = gin.H{
+			"id":         msg.ID,
+			"santa_id":   msg.SantaID,
+			"giftee_id":  msg.GifteeID,
+			"from_santa": msg.FromSanta,
+			"content":    decryptedContent,
+			"read_at":    msg.ReadAt,
+			"edited_at":  msg.EditedAt,
+			"deleted_at": msg.DeletedAt,
+			"attachment": chatAttachment(&msg, h.encryptionKey),
+			"created_at": msg.CreatedAt,
+		}
+	}
+	return chatMessages
+}
+
+// MarkGifteeChatRead - отметить прочитанными сообщения от получателя (я - даритель)
+func (h *Handler) MarkGifteeChatRead(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var member models.Member
+	if err := h.DB.First(&member, "group_id = ? AND user_id = ?", groupID, c.GetString("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
+		return
+	}
+
+	if member.GifteeID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Draw has not been performed yet"})
+		return
+	}
+
+	h.markChatRead(c, groupID, member.ID, *member.GifteeID, ChatWithGiftee)
+}
+
+// MarkSantaChatRead - отметить прочитанными сообщения от дарителя (я - получатель)
+func (h *Handler) MarkSantaChatRead(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var member models.Member
+	if err := h.DB.First(&member, "group_id = ? AND user_id = ?", groupID, c.GetString("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
+		return
+	}
+
+	var santa models.Member
+	if err := h.DB.First(&santa, "group_id = ? AND giftee_id = ?", groupID, member.ID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Draw has not been performed yet or you don't have a santa"})
+		return
+	}
+
+	h.markChatRead(c, groupID, member.ID, santa.ID, ChatWithSanta)
+}
+
+func (h *Handler) markChatRead(c *gin.Context, groupID, readerID, partnerID uuid.UUID, chat string) {
+	var req MarkReadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if len(req.MessageIDs) > MaxReadBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many messages"})
+		return
+	}
+
+	marked, err := h.Hub.MarkRead(groupID, readerID, partnerID, chat, req.MessageIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark messages read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
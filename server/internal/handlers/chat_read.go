@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secret-santa/internal/models"
+)
+
+// MaxReadBatch - сколько сообщений можно отметить прочитанными одним событием
+const MaxReadBatch = 500
+
+// readEventChunk - сколько ID сообщений помещается в одну квитанцию: событие проходит через
+// pubsub, а Postgres ограничивает NOTIFY ~8000 байтами (UUID в JSON занимает 39 байт)
+const readEventChunk = 150
+
+// ReadEvent - сообщения чата пары прочитаны
+type ReadEvent struct {
+	Type       string      `json:"type"` // "read"
+	Chat       string      `json:"chat"` // ChatWithGiftee или ChatWithSanta с точки зрения получателя события
+	MessageIDs []uuid.UUID `json:"message_ids"`
+	ReadAt     time.Time   `json:"read_at"`
+}
+
+// UnreadEvent - актуальные счетчики непрочитанных участника в розыгрыше
+type UnreadEvent struct {
+	Type             string `json:"type"` // "unread"
+	UnreadFromGiftee int64  `json:"unread_from_giftee"`
+	UnreadFromSanta  int64  `json:"unread_from_santa"`
+	Total            int64  `json:"total"`
+}
+
+// unreadCounts считает непрочитанные сообщения участника: от его получателя и от его дарителя
+func (h *Hub) unreadCounts(groupID, memberID uuid.UUID) (fromGiftee, fromSanta int64) {
+	// Я - даритель: непрочитанные от получателя
+	h.db.Model(&models.Message{}).
+		Where("group_id = ? AND santa_id = ? AND from_santa = false AND read_at IS NULL AND deleted_at IS NULL", groupID, memberID).
+		Count(&fromGiftee)
+
+	// Я - получатель: непрочитанные от дарителя
+	h.db.Model(&models.Message{}).
+		Where("group_id = ? AND giftee_id = ? AND from_santa = true AND read_at IS NULL AND deleted_at IS NULL", groupID, memberID).
+		Count(&fromSanta)
+
+	return fromGiftee, fromSanta
+}
+
+// pushUnread отправляет участнику (во все его вкладки) актуальные счетчики непрочитанных
+func (h *Hub) pushUnread(groupID, memberID uuid.UUID) {
+	fromGiftee, fromSanta := h.unreadCounts(groupID, memberID)
+	h.NotifyMember(groupID, memberID, UnreadEvent{
+		Type:             EventUnread,
+		UnreadFromGiftee: fromGiftee,
+		UnreadFromSanta:  fromSanta,
+		Total:            fromGiftee + fromSanta,
+	})
+}
+
+// MarkRead отмечает прочитанными сообщения собеседника в чате пары.
+// chat - чат с точки зрения читающего (ChatWithGiftee: читает даритель), ids = nil - все непрочитанные.
+// Собеседник получает квитанцию о прочтении, вкладки читающего - новые счетчики.
+func (h *Hub) MarkRead(groupID, readerID, partnerID uuid.UUID, chat string, ids []uuid.UUID) (int, error) {
+	query := h.db.Model(&models.Message{}).Where("group_id = ? AND read_at IS NULL", groupID)
+	if chat == ChatWithGiftee {
+		query = query.Where("santa_id = ? AND giftee_id = ? AND from_santa = false", readerID, partnerID)
+	} else {
+		query = query.Where("santa_id = ? AND giftee_id = ? AND from_santa = true", partnerID, readerID)
+	}
+	if ids != nil {
+		if len(ids) == 0 {
+			return 0, nil
+		}
+		query = query.Where("id IN ?", ids)
+	}
+
+	var unread []uuid.UUID
+	if err := query.Pluck("id", &unread).Error; err != nil {
+		return 0, err
+	}
+	if len(unread) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	if err := h.db.Model(&models.Message{}).
+		Where("id IN ? AND read_at IS NULL", unread).
+		Update("read_at", now).Error; err != nil {
+		return 0, err
+	}
+
+	partnerChat := ChatWithSanta
+	if chat == ChatWithSanta {
+		partnerChat = ChatWithGiftee
+	}
+
+	// Квитанция собеседнику и синхронизация остальных вкладок читающего
+	for _, chunk := range chunkIDs(unread, readEventChunk) {
+		h.NotifyMember(groupID, partnerID, ReadEvent{Type: EventRead, Chat: partnerChat, MessageIDs: chunk, ReadAt: now})
+		h.NotifyMember(groupID, readerID, ReadEvent{Type: EventRead, Chat: chat, MessageIDs: chunk, ReadAt: now})
+	}
+	h.pushUnread(groupID, readerID)
+
+	return len(unread), nil
+}
+
+// chunkIDs делит список ID на части не больше size
+func chunkIDs(ids []uuid.UUID, size int) [][]uuid.UUID {
+	var chunks [][]uuid.UUID
+	for len(ids) > size {
+		chunks = append(chunks, ids[:size:size])
+		ids = ids[size:]
+	}
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+	return chunks
+}
+
+// handleRead отмечает прочитанными сообщения из события клиента
+func (c *Client) handleRead(event IncomingEvent) {
+	if len(event.MessageIDs) > MaxReadBatch {
+		c.sendError(event.ClientID, "Too many messages")
+		return
+	}
+
+	// role - в каком чате читаю: "santa" - в чате со своим получателем
+	chat, partnerID := ChatWithSanta, c.santaID
+	if event.Role == "santa" {
+		chat, partnerID = ChatWithGiftee, c.gifteeID
+	}
+	if partnerID == uuid.Nil {
+		c.sendError(event.ClientID, "Chat is not available")
+		return
+	}
+
+	ids := event.MessageIDs
+	if ids == nil {
+		ids = []uuid.UUID{}
+	}
+	if _, err := c.hub.MarkRead(c.groupID, c.memberID, partnerID, chat, ids); err != nil {
+		log.Printf("Failed to mark messages read: %v", err)
+		c.sendError(event.ClientID, "Failed to mark messages read")
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+
+	"secret-santa/internal/models"
+)
+
+// ChatTicketTTL - сколько живет билет на подключение к WebSocket чата
+const ChatTicketTTL = 30 * time.Second
+
+var errInvalidTicket = errors.New("invalid or expired ticket")
+
+// ChatTicketResponse - билет для ?ticket= при подключении к /raffles/:id/chat/ws
+type ChatTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateChatTicket - выдать одноразовый билет на подключение к чату розыгрыша
+func (h *Handler) CreateChatTicket(c *gin.Context) {
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var member models.Member
+	if err := h.DB.First(&member, "group_id = ? AND user_id = ?", groupID, userID).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this raffle"})
+		return
+	}
+
+	var group models.Group
+	if err := h.DB.First(&group, "id = ?", groupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	if !group.IsDrawn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Draw has not been performed yet"})
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ticket"})
+		return
+	}
+	ticket := hex.EncodeToString(b)
+
+	// Заодно убираем просроченные билеты, которыми так и не воспользовались
+	if err := h.DB.Where("expires_at < ?", time.Now()).Delete(&models.ChatTicket{}).Error; err != nil {
+		log.Printf("Failed to clean up chat tickets: %v", err)
+	}
+
+	record := models.ChatTicket{
+		TokenHash: ticketHash(ticket),
+		UserID:    userID,
+		GroupID:   groupID,
+		ExpiresAt: time.Now().Add(ChatTicketTTL),
+	}
+	if err := h.DB.Create(&record).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ticket"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ChatTicketResponse{Ticket: ticket, ExpiresAt: record.ExpiresAt})
+}
+
+// consumeChatTicket погашает билет и возвращает пользователя, которому он выдан.
+// Удаление с RETURNING атомарно: даже при нескольких экземплярах API билет срабатывает один раз.
+func (h *Handler) consumeChatTicket(groupID uuid.UUID, ticket string) (uuid.UUID, error) {
+	var record models.ChatTicket
+	result := h.DB.Clauses(clause.Returning{}).
+		Where("token_hash = ? AND group_id = ?", ticketHash(ticket), groupID).
+		Delete(&record)
+	if result.Error != nil {
+		return uuid.Nil, result.Error
+	}
+	if result.RowsAffected == 0 || time.Now().After(record.ExpiresAt) {
+		return uuid.Nil, errInvalidTicket
+	}
+	return record.UserID, nil
+}
+
+func ticketHash(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"secret-santa/internal/currency"
+	"secret-santa/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// ExchangeRatesRequest - запрос на обновление курсов валют
+type ExchangeRatesRequest struct {
+	Rates map[string]float64 `json:"rates" binding:"required"`
+}
+
+// GetExchangeRates - получить текущую таблицу курсов
+func (h *Handler) GetExchangeRates(c *gin.Context) {
+	var rates []models.ExchangeRate
+	if err := h.DB.Order("currency ASC").Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base":  currency.BaseCurrency,
+		"rates": rates,
+	})
+}
+
+// UpdateExchangeRates - обновить курсы валют (только для администраторов)
+func (h *Handler) UpdateExchangeRates(c *gin.Context) {
+	var req ExchangeRatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rates := make(currency.Rates, len(req.Rates))
+	for code, rate := range req.Rates {
+		code = strings.ToUpper(code)
+		if !currency.IsValid(code) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency code: " + code})
+			return
+		}
+		if rate <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Rate must be positive: " + code})
+			return
+		}
+		rates[code] = rate
+	}
+
+	if err := h.ImportExchangeRates(rates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exchange rates"})
+		return
+	}
+
+	h.GetExchangeRates(c)
+}
+
+// ReloadExchangeRates - перечитать курсы из файла EXCHANGE_RATES_FILE (только для администраторов)
+func (h *Handler) ReloadExchangeRates(c *gin.Context) {
+	if h.cfg.ExchangeRatesFile == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exchange rates file is not configured"})
+		return
+	}
+
+	rates, err := currency.LoadRatesFile(h.cfg.ExchangeRatesFile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.ImportExchangeRates(rates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exchange rates"})
+		return
+	}
+
+	h.GetExchangeRates(c)
+}
+
+// ImportExchangeRates сохраняет курсы в БД (существующие перезаписываются)
+func (h *Handler) ImportExchangeRates(rates currency.Rates) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	records := make([]models.ExchangeRate, 0, len(rates))
+	for code, rate := range rates {
+		records = append(records, models.ExchangeRate{Currency: code, Rate: rate})
+	}
+
+	return h.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(&records).Error
+}
+
+// loadExchangeRates загружает таблицу курсов из БД
+func (h *Handler) loadExchangeRates() currency.Rates {
+	var records []models.ExchangeRate
+	if err := h.DB.Find(&records).Error; err != nil {
+		log.Printf("Failed to load exchange rates: %v", err)
+	}
+
+	rates := make(currency.Rates, len(records))
+	for _, r := range records {
+		rates[r.Currency] = r.Rate
+	}
+	return rates
+}
+
+// groupBudget возвращает структурированный бюджет розыгрыша (nil для старых розыгрышей)
+func groupBudget(g models.Group) *currency.Budget {
+	if g.BudgetCurrency == nil || (g.BudgetMin == nil && g.BudgetMax == nil) {
+		return nil
+	}
+	return &currency.Budget{Min: g.BudgetMin, Max: g.BudgetMax, Currency: *g.BudgetCurrency}
+}
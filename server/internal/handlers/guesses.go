@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"secret-santa/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Очки игры "Угадай Санту"
+const (
+	guessCorrectPoints  = 10 // За верную догадку
+	guessEarlyBonusMax  = 5  // Бонус за раннюю догадку: +1 за каждый полный день до раскрытия
+	guessEarlyBonusUnit = 24 * time.Hour
+)
+
+// errGuessesLocked - розыгрыш раскрыли, пока сохранялась догадка
+var errGuessesLocked = errors.New("guesses are locked")
+
+// SantaGuessRequest - догадка получателя
+type SantaGuessRequest struct {
+	MemberID string `json:"memberId" binding:"required"` // Кого я считаю своим дарителем
+}
+
+// SantaGuessResponse - моя догадка (результат после раскрытия)
+type SantaGuessResponse struct {
+	MemberID  string `json:"memberId"`
+	Locked    bool   `json:"locked"`  // Дарители раскрыты - догадку уже не изменить
+	Correct   *bool  `json:"correct"` // nil - до раскрытия или даритель остался анонимным
+	Points    int    `json:"points"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// LeaderboardEntryResponse - строка рейтинга
+type LeaderboardEntryResponse struct {
+	UserID         string  `json:"userId"`
+	Name           string  `json:"name"`
+	AvatarURL      *string `json:"avatarUrl"`
+	Points         int     `json:"points"`
+	CorrectGuesses int     `json:"correctGuesses"`
+	Guesses        int     `json:"guesses"` // Оцененные догадки
+	Raffles        int     `json:"raffles"` // Сколько розыгрышей учтено
+}
+
+// GetMySantaGuess - моя догадка в розыгрыше
+func (h *Handler) GetMySantaGuess(c *gin.Context) {
+	group, member, ok := h.guessMember(c)
+	if !ok {
+		return
+	}
+
+	var guess models.SantaGuess
+	if err := h.DB.Where("group_id = ? AND giftee_id = ?", group.ID, member.ID).First(&guess).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No guess yet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, santaGuessToResponse(guess, group))
+}
+
+// SubmitSantaGuess - получатель указывает, кого считает своим дарителем (до раскрытия)
+func (h *Handler) SubmitSantaGuess(c *gin.Context) {
+	group, member, ok := h.guessMember(c)
+	if !ok {
+		return
+	}
+
+	var req SantaGuessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !group.IsDrawn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Draw not yet conducted"})
+		return
+	}
+
+	if group.RevealedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Guesses are locked after santas are revealed"})
+		return
+	}
+
+	guessedID, err := uuid.Parse(req.MemberID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid member ID"})
+		return
+	}
+	if guessedID == member.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot be your own santa"})
+		return
+	}
+
+	var count int64
+	h.DB.Model(&models.Member{}).Where("id = ? AND group_id = ?", guessedID, group.ID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Member not found in this raffle"})
+		return
+	}
+
+	guess := models.SantaGuess{
+		GroupID:  group.ID,
+		GifteeID: member.ID,
+	}
+	// Раскрытие первым делом обновляет строку розыгрыша, поэтому блокировка этой строки
+	// не дает сохранить догадку, пока раскрытие оценивает догадки, или после него
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var locked models.Group
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", group.ID).Error; err != nil {
+			return err
+		}
+		if locked.RevealedAt != nil {
+			group.RevealedAt = locked.RevealedAt
+			return errGuessesLocked
+		}
+
+		tx.Where("group_id = ? AND giftee_id = ?", group.ID, member.ID).First(&guess)
+		guess.GuessedMemberID = guessedID
+		return tx.Save(&guess).Error
+	})
+	if errors.Is(err, errGuessesLocked) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Guesses are locked after santas are revealed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save guess"})
+		return
+	}
+
+	c.JSON(http.StatusOK, santaGuessToResponse(guess, group))
+}
+
+// GetRaffleLeaderboard - рейтинг "Угадай Санту" в розыгрыше (после раскрытия)
+func (h *Handler) GetRaffleLeaderboard(c *gin.Context) {
+	group, _, ok := h.guessMember(c)
+	if !ok {
+		return
+	}
+
+	if group.RevealedAt == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Santas have not been revealed yet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.leaderboard([]uuid.UUID{group.ID}))
+}
+
+// GetSeriesLeaderboard - общий рейтинг по всем раскрытым розыгрышам серии
+func (h *Handler) GetSeriesLeaderboard(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	seriesID, err := uuid.Parse(c.Param("seriesId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	var groups []models.Group
+	h.DB.Where("series_id = ?", seriesID).Find(&groups)
+	if len(groups) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+
+	groupIDs := make([]uuid.UUID, 0, len(groups))
+	allIDs := make([]uuid.UUID, len(groups))
+	for i, g := range groups {
+		allIDs[i] = g.ID
+		if g.RevealedAt != nil {
+			groupIDs = append(groupIDs, g.ID)
+		}
+	}
+
+	// Смотреть рейтинг серии могут участники любого ее розыгрыша
+	var count int64
+	h.DB.Model(&models.Member{}).Where("group_id IN ? AND user_id = ?", allIDs, uid).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.leaderboard(groupIDs))
+}
+
+// guessMember находит розыгрыш и участника текущего пользователя
+func (h *Handler) guessMember(c *gin.Context) (models.Group, models.Member, bool) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return models.Group{}, models.Member{}, false
+	}
+
+	var group models.Group
+	if err := h.DB.First(&group, rid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return models.Group{}, models.Member{}, false
+	}
+
+	var member models.Member
+	if err := h.DB.Where("group_id = ? AND user_id = ?", rid, uid).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
+		return models.Group{}, models.Member{}, false
+	}
+
+	return group, member, true
+}
+
+// scoreGuesses оценивает догадки при раскрытии розыгрыша.
+// Даритель ищется так же, как в чате: участник с giftee_id = ID получателя.
+// Вызывается в транзакции раскрытия: розыгрыш не станет раскрытым без оценки догадок.
+func scoreGuesses(tx *gorm.DB, groupID uuid.UUID, revealedAt time.Time) error {
+	var members []models.Member
+	if err := tx.Where("group_id = ?", groupID).Find(&members).Error; err != nil {
+		return err
+	}
+
+	santaOf := make(map[uuid.UUID]models.Member, len(members))
+	for _, m := range members {
+		if m.GifteeID != nil {
+			santaOf[*m.GifteeID] = m
+		}
+	}
+
+	var guesses []models.SantaGuess
+	if err := tx.Where("group_id = ?", groupID).Find(&guesses).Error; err != nil {
+		return err
+	}
+
+	for _, guess := range guesses {
+		santa, ok := santaOf[guess.GifteeID]
+		var correct *bool
+		points := 0
+
+		// Анонимного дарителя не оцениваем: результат раскрыл бы его
+		if ok && !santa.StayAnonymous {
+			isCorrect := santa.ID == guess.GuessedMemberID
+			correct = &isCorrect
+			if isCorrect {
+				points = guessCorrectPoints + guessEarlyBonus(guess.UpdatedAt, revealedAt)
+			}
+		}
+
+		if err := tx.Model(&models.SantaGuess{}).Where("id = ?", guess.ID).
+			Updates(map[string]interface{}{"correct": correct, "points": points}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// guessEarlyBonus - бонус за то, что догадка не менялась задолго до раскрытия
+func guessEarlyBonus(guessedAt, revealedAt time.Time) int {
+	days := int(revealedAt.Sub(guessedAt) / guessEarlyBonusUnit)
+	if days < 0 {
+		return 0
+	}
+	if days > guessEarlyBonusMax {
+		return guessEarlyBonusMax
+	}
+	return days
+}
+
+// leaderboard суммирует очки по пользователям в указанных розыгрышах
+func (h *Handler) leaderboard(groupIDs []uuid.UUID) []LeaderboardEntryResponse {
+	entries := []LeaderboardEntryResponse{}
+	if len(groupIDs) == 0 {
+		return entries
+	}
+
+	var members []models.Member
+	h.DB.Preload("User").Where("group_id IN ?", groupIDs).Find(&members)
+
+	var guesses []models.SantaGuess
+	h.DB.Where("group_id IN ?", groupIDs).Find(&guesses)
+	guessByGiftee := make(map[uuid.UUID]models.SantaGuess, len(guesses))
+	for _, g := range guesses {
+		guessByGiftee[g.GifteeID] = g
+	}
+
+	byUser := make(map[uuid.UUID]*LeaderboardEntryResponse)
+	for _, m := range members {
+		entry, ok := byUser[m.UserID]
+		if !ok {
+			entry = &LeaderboardEntryResponse{
+				UserID:    m.UserID.String(),
+				Name:      m.User.Name,
+				AvatarURL: m.User.AvatarURL,
+			}
+			byUser[m.UserID] = entry
+		}
+		entry.Raffles++
+
+		guess, ok := guessByGiftee[m.ID]
+		if !ok || guess.Correct == nil {
+			continue
+		}
+		entry.Guesses++
+		entry.Points += guess.Points
+		if *guess.Correct {
+			entry.CorrectGuesses++
+		}
+	}
+
+	for _, entry := range byUser {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Points != entries[j].Points {
+			return entries[i].Points > entries[j].Points
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+func santaGuessToResponse(g models.SantaGuess, group models.Group) SantaGuessResponse {
+	return SantaGuessResponse{
+		MemberID:  g.GuessedMemberID.String(),
+		Locked:    group.RevealedAt != nil,
+		Correct:   g.Correct,
+		Points:    g.Points,
+		UpdatedAt: g.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"sync"
+
 	"secret-santa/internal/config"
 	"secret-santa/internal/storage"
+	"secret-santa/internal/tracking"
+	"secret-santa/internal/unfurl"
 
+	"github.com/gorilla/websocket"
 	"gorm.io/gorm"
 )
 
@@ -12,15 +17,30 @@ type Handler struct {
 	cfg           *config.Config
 	storage       *storage.S3Storage
 	Hub           *Hub
+	Unfurler      unfurl.Fetcher     // Загрузчик превью ссылок (можно подменить локальным стендом)
+	Tracker       *tracking.Registry // Адаптеры перевозчиков для опроса статусов посылок
 	encryptionKey []byte
+	upgrader      websocket.Upgrader // Проверяет Origin по cfg.CorsOrigins
+
+	previewsInFlight sync.Map // URL -> struct{}: превью, которые сейчас загружаются
 }
 
 func New(db *gorm.DB, cfg *config.Config, s3 *storage.S3Storage, hub *Hub) *Handler {
+	var carriers []tracking.Carrier
+	if cfg.TrackingAPIURL != "" {
+		for _, code := range tracking.AllCarriers {
+			carriers = append(carriers, tracking.NewHTTPCarrier(code, cfg.TrackingAPIURL, cfg.TrackingAPIKey))
+		}
+	}
+
 	return &Handler{
 		DB:            db,
 		cfg:           cfg,
 		storage:       s3,
 		Hub:           hub,
+		Unfurler:      unfurl.NewHTTPFetcher(unfurl.Options{}),
+		Tracker:       tracking.NewRegistry(carriers...),
 		encryptionKey: cfg.EncryptionKey,
+		upgrader:      newUpgrader(cfg.CorsOrigins),
 	}
 }
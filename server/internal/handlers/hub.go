@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 
 	"secret-santa/internal/crypto"
 	"secret-santa/internal/models"
+	"secret-santa/internal/pubsub"
 	"secret-santa/internal/validator"
 )
 
@@ -21,28 +23,44 @@ type Client struct {
 	hub      *Hub
 	conn     *websocket.Conn
 	send     chan []byte
+	done     chan struct{} // Закрывается, когда клиента нужно отключить (send не закрывается никогда)
+	once     sync.Once
 	userID   uuid.UUID
 	memberID uuid.UUID // ID участника в конкретном розыгрыше
 	groupID  uuid.UUID
+	santaID  uuid.UUID // Мой даритель (uuid.Nil, если его нет)
+	gifteeID uuid.UUID // Мой получатель (uuid.Nil, если его нет)
 }
 
-// Hub управляет всеми WebSocket соединениями
+// ClientInfo - кто подключается к чату
+type ClientInfo struct {
+	UserID   uuid.UUID
+	GroupID  uuid.UUID
+	MemberID uuid.UUID
+	SantaID  uuid.UUID // uuid.Nil, если у участника нет дарителя
+	GifteeID uuid.UUID // uuid.Nil, если у участника нет получателя
+}
+
+// Hub управляет всеми WebSocket соединениями.
+// Клиенты разложены по комнатам розыгрышей, внутри комнаты - по участникам,
+// поэтому рассылка стоит O(число получателей), а не O(все соединения сервера).
 type Hub struct {
-	clients       map[*Client]bool
-	broadcast     chan *BroadcastMessage
-	register      chan *Client
-	unregister    chan *Client
+	mu            sync.Mutex
+	rooms         map[uuid.UUID]*room // ID розыгрыша -> комната
 	db            *gorm.DB
 	encryptionKey []byte
-	mu            sync.RWMutex
+	ps            pubsub.PubSub // Доставка событий между экземплярами API
+	instanceID    uuid.UUID     // Этот экземпляр API в событиях присутствия
+	outbox        *outbox
+	presence      presenceState
+
+	loadMessage func(id uuid.UUID) (*ChatMessage, error) // Чтение сообщения для рассылки (в тестах - без БД)
 }
 
-// BroadcastMessage представляет сообщение для broadcast
-type BroadcastMessage struct {
-	GroupID  uuid.UUID
-	SantaID  uuid.UUID
-	GifteeID uuid.UUID
-	Message  *ChatMessage
+// room - подключенные участники одного розыгрыша
+type room struct {
+	mu      sync.RWMutex
+	members map[uuid.UUID]map[*Client]struct{} // ID участника -> его вкладки
 }
 
 // ChatMessage представляет структуру сообщения в чате
@@ -53,7 +71,11 @@ type ChatMessage struct {
 	FromSanta bool       `json:"from_santa"`
 	Content   string     `json:"content"`
 	ReadAt    *time.Time `json:"read_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	DeletedAt *time.Time `json:"deleted_at"` // У удаленного сообщения Content пустой
 	CreatedAt time.Time  `json:"created_at"`
+
+	Attachment *ChatAttachment `json:"attachment,omitempty"` // Ссылку на файл выдает GetChatAttachmentURL
 }
 
 const (
@@ -61,72 +83,223 @@ const (
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
 	maxMessageSize = 512 * 1024 // 512 KB
+	sendBufferSize = 256        // Событий в очереди клиента; переполнение - медленный клиент отключается
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// В production нужно проверять origin
-		return true
-	},
+// newUpgrader создает WebSocket upgrader, пропускающий только разрешенные origin (те же, что и для CORS).
+// Запросы без Origin (не из браузера) пропускаются - от CSRF через WebSocket защищает проверка браузерного origin.
+func newUpgrader(allowedOrigins []string) websocket.Upgrader {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[normalizeOrigin(origin)] = true
+	}
+
+	return websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" || allowed["*"] {
+				return true
+			}
+			return allowed[normalizeOrigin(origin)]
+		},
+	}
+}
+
+func normalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
 }
 
 // NewHub создает новый Hub
-func NewHub(db *gorm.DB, encryptionKey []byte) *Hub {
-	return &Hub{
-		clients:       make(map[*Client]bool),
-		broadcast:     make(chan *BroadcastMessage),
-		register:      make(chan *Client),
-		unregister:    make(chan *Client),
+func NewHub(db *gorm.DB, encryptionKey []byte, ps pubsub.PubSub) *Hub {
+	h := &Hub{
+		rooms:         make(map[uuid.UUID]*room),
 		db:            db,
 		encryptionKey: encryptionKey,
+		ps:            ps,
+		instanceID:    uuid.New(),
+		outbox:        newOutbox(),
+		presence: presenceState{
+			members:   make(map[presenceKey]*memberPresence),
+			instances: make(map[uuid.UUID]time.Time),
+		},
 	}
+	h.loadMessage = h.loadChatMessage
+	return h
 }
 
-// Run запускает Hub
-func (h *Hub) Run() {
-	for {
-		select {
-		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client] = true
-			h.mu.Unlock()
-			log.Printf("Client registered: user=%s, member=%s, group=%s",
-				client.userID, client.memberID, client.groupID)
-
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-				log.Printf("Client unregistered: user=%s", client.userID)
-			}
-			h.mu.Unlock()
-
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			// Отправляем сообщение только участникам этой пары (santa и giftee)
-			for client := range h.clients {
-				if client.groupID == message.GroupID &&
-					(client.memberID == message.SantaID || client.memberID == message.GifteeID) {
-					select {
-					case client.send <- mustMarshal(message.Message):
-					default:
-						close(client.send)
-						delete(h.clients, client)
-					}
-				}
-			}
-			h.mu.RUnlock()
+// Attach регистрирует WebSocket соединение в хабе и запускает его чтение и запись
+func (h *Hub) Attach(conn *websocket.Conn, info ClientInfo) {
+	client := &Client{
+		hub:      h,
+		conn:     conn,
+		send:     make(chan []byte, sendBufferSize),
+		done:     make(chan struct{}),
+		userID:   info.UserID,
+		memberID: info.MemberID,
+		groupID:  info.GroupID,
+		santaID:  info.SantaID,
+		gifteeID: info.GifteeID,
+	}
+
+	h.register(client)
+
+	// Запускаем горутины для чтения и записи
+	go client.writePump()
+	go client.readPump()
+}
+
+// Stats возвращает число комнат и соединений на этом экземпляре
+func (h *Hub) Stats() (rooms, clients int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range h.rooms {
+		r.mu.RLock()
+		for _, tabs := range r.members {
+			clients += len(tabs)
+		}
+		r.mu.RUnlock()
+	}
+	return len(h.rooms), clients
+}
+
+// register добавляет клиента в комнату розыгрыша
+func (h *Hub) register(client *Client) {
+	// Порядок блокировок: h.mu, затем room.mu
+	h.mu.Lock()
+	r, ok := h.rooms[client.groupID]
+	if !ok {
+		r = &room{members: make(map[uuid.UUID]map[*Client]struct{})}
+		h.rooms[client.groupID] = r
+	}
+	r.mu.Lock()
+	h.mu.Unlock()
+
+	tabs, wasOnline := r.members[client.memberID]
+	if !wasOnline {
+		tabs = make(map[*Client]struct{})
+		r.members[client.memberID] = tabs
+	}
+	tabs[client] = struct{}{}
+	if !wasOnline {
+		h.announcePresence(client, true)
+	}
+	r.mu.Unlock()
+
+	log.Printf("Client registered: user=%s, member=%s, group=%s",
+		client.userID, client.memberID, client.groupID)
+
+	// Текущее состояние собеседников новому клиенту (с учетом других экземпляров API)
+	if client.gifteeID != uuid.Nil {
+		online := h.memberOnline(client.groupID, client.gifteeID)
+		client.trySend(mustMarshal(PresenceEvent{Type: EventPresence, Chat: ChatWithGiftee, Online: online}))
+	}
+	if client.santaID != uuid.Nil {
+		online := h.memberOnline(client.groupID, client.santaID)
+		client.trySend(mustMarshal(PresenceEvent{Type: EventPresence, Chat: ChatWithSanta, Online: online}))
+	}
+}
+
+// unregister убирает клиента из комнаты; если это была последняя вкладка участника,
+// собеседники видят его не в сети
+func (h *Hub) unregister(client *Client) {
+	client.close()
+
+	h.mu.Lock()
+	r, ok := h.rooms[client.groupID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	r.mu.Lock()
+	tabs := r.members[client.memberID]
+	if _, registered := tabs[client]; !registered {
+		r.mu.Unlock()
+		h.mu.Unlock()
+		return
+	}
+	delete(tabs, client)
+	if len(tabs) == 0 {
+		delete(r.members, client.memberID)
+		h.announcePresence(client, false)
+	}
+	if len(r.members) == 0 {
+		delete(h.rooms, client.groupID)
+	}
+	r.mu.Unlock()
+	h.mu.Unlock()
+
+	log.Printf("Client unregistered: user=%s", client.userID)
+}
+
+// deliver отправляет событие во все локальные вкладки участников розыгрыша.
+// Клиенты, не успевающие читать (буфер полон), отключаются и переподключаются сами.
+func (h *Hub) deliver(groupID uuid.UUID, data []byte, memberIDs ...uuid.UUID) {
+	h.mu.Lock()
+	r := h.rooms[groupID]
+	h.mu.Unlock()
+	if r == nil {
+		return
+	}
+
+	var recipients []*Client
+	r.mu.RLock()
+	for _, memberID := range memberIDs {
+		for client := range r.members[memberID] {
+			recipients = append(recipients, client)
 		}
 	}
+	r.mu.RUnlock()
+
+	for _, client := range recipients {
+		if !client.trySend(data) {
+			log.Printf("Slow client disconnected: user=%s, member=%s", client.userID, client.memberID)
+			client.close()
+		}
+	}
+}
+
+// isOnline - у участника есть хотя бы одно соединение с этим экземпляром
+func (h *Hub) isOnline(groupID, memberID uuid.UUID) bool {
+	h.mu.Lock()
+	r := h.rooms[groupID]
+	h.mu.Unlock()
+	if r == nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.members[memberID]) > 0
+}
+
+// trySend кладет событие в очередь клиента без ожидания; false - очередь полна
+func (c *Client) trySend(data []byte) bool {
+	select {
+	case <-c.done:
+		return true // Клиент уже отключается, событие ему не нужно
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// close просит writePump закрыть соединение (безопасно вызывать многократно и из любых горутин)
+func (c *Client) close() {
+	c.once.Do(func() { close(c.done) })
 }
 
 // readPump читает сообщения от клиента
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.unregister(c)
 		c.conn.Close()
 	}()
 
@@ -146,102 +319,127 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// Парсим входящее сообщение
-		var incomingMsg struct {
-			Content string `json:"content"`
-			Role    string `json:"role"` // "santa" или "giftee"
-		}
-		if err := json.Unmarshal(message, &incomingMsg); err != nil {
+		var event IncomingEvent
+		if err := json.Unmarshal(message, &event); err != nil {
 			log.Printf("Invalid message format: %v", err)
+			c.sendError("", "Invalid message format")
 			continue
 		}
 
-		// Валидируем сообщение
-		incomingMsg.Content = validator.SanitizeString(incomingMsg.Content)
-		if err := validator.ValidateMessage(incomingMsg.Content); err != nil {
-			log.Printf("Message validation failed: %v", err)
-			// Отправляем ошибку клиенту
-			errorMsg := map[string]string{"error": "Invalid message: " + err.Error()}
-			if errData, _ := json.Marshal(errorMsg); errData != nil {
-				c.send <- errData
-			}
-			continue
+		switch event.Type {
+		case EventMessage, "":
+			c.handleMessage(event)
+		case EventTyping:
+			c.handleTyping(event)
+		case EventRead:
+			c.handleRead(event)
+		default:
+			c.sendError(event.ClientID, "Unknown event type")
 		}
+	}
+}
 
-		// Получаем информацию о текущем участнике
-		var member models.Member
-		if err := c.hub.db.First(&member, "id = ?", c.memberID).Error; err != nil {
-			log.Printf("Member not found: %v", err)
-			continue
-		}
+// counterpart возвращает пару (даритель, получатель) для чата, в который пишет клиент
+func (c *Client) counterpart(role string) (santaID, gifteeID uuid.UUID, fromSanta bool, ok bool) {
+	if role == "santa" {
+		// Я пишу как Санта своему получателю
+		return c.memberID, c.gifteeID, true, c.gifteeID != uuid.Nil
+	}
+	// Я пишу как Получатель своему Санте
+	return c.santaID, c.memberID, false, c.santaID != uuid.Nil
+}
 
-		// Проверяем, что жеребьевка проведена
-		if member.GifteeID == nil {
-			log.Printf("Draw not performed yet for member %s", c.memberID)
-			continue
-		}
+// handleMessage сохраняет сообщение и рассылает его обоим участникам пары
+func (c *Client) handleMessage(event IncomingEvent) {
+	// Валидируем сообщение
+	event.Content = validator.SanitizeString(event.Content)
+	if err := validator.ValidateMessage(event.Content); err != nil {
+		log.Printf("Message validation failed: %v", err)
+		c.sendError(event.ClientID, "Invalid message: "+err.Error())
+		return
+	}
 
-		// Определяем, кто отправитель и получатель на основе роли
-		var santaID, gifteeID uuid.UUID
-		var fromSanta bool
-
-		if incomingMsg.Role == "santa" {
-			// Я пишу как Санта своему получателю
-			santaID = c.memberID
-			gifteeID = *member.GifteeID
-			fromSanta = true
-		} else {
-			// Я пишу как Получатель своему Санте
-			// Находим моего Санту
-			var santa models.Member
-			if err := c.hub.db.First(&santa, "giftee_id = ?", c.memberID).Error; err != nil {
-				log.Printf("Santa not found for giftee %s: %v", c.memberID, err)
-				continue
-			}
-			santaID = santa.ID
-			gifteeID = c.memberID
-			fromSanta = false
-		}
+	// Определяем, кто отправитель и получатель на основе роли
+	santaID, gifteeID, fromSanta, ok := c.counterpart(event.Role)
+	if !ok {
+		c.sendError(event.ClientID, "Chat is not available")
+		return
+	}
 
-		// Шифруем содержимое сообщения перед сохранением в БД
-		encryptedContent, err := crypto.Encrypt(incomingMsg.Content, c.hub.encryptionKey)
-		if err != nil {
-			log.Printf("Failed to encrypt message: %v", err)
-			continue
-		}
+	// Шифруем содержимое сообщения перед сохранением в БД
+	encryptedContent, err := crypto.Encrypt(event.Content, c.hub.encryptionKey)
+	if err != nil {
+		log.Printf("Failed to encrypt message: %v", err)
+		c.sendError(event.ClientID, "Failed to send message")
+		return
+	}
 
-		// Сохраняем сообщение в БД (в зашифрованном виде)
-		dbMessage := models.Message{
-			GroupID:   c.groupID,
-			SantaID:   santaID,
-			GifteeID:  gifteeID,
-			FromSanta: fromSanta,
-			Content:   encryptedContent, // Зашифрованный текст
-		}
+	// Сохраняем сообщение в БД (в зашифрованном виде)
+	dbMessage := models.Message{
+		GroupID:   c.groupID,
+		SantaID:   santaID,
+		GifteeID:  gifteeID,
+		FromSanta: fromSanta,
+		Content:   encryptedContent, // Зашифрованный текст
+	}
 
-		if err := c.hub.db.Create(&dbMessage).Error; err != nil {
-			log.Printf("Failed to save message: %v", err)
-			continue
-		}
+	if err := c.hub.db.Create(&dbMessage).Error; err != nil {
+		log.Printf("Failed to save message: %v", err)
+		c.sendError(event.ClientID, "Failed to send message")
+		return
+	}
 
-		// Отправляем сообщение всем участникам этой пары (в оригинальном виде)
-		chatMsg := &ChatMessage{
-			ID:        dbMessage.ID,
-			SantaID:   santaID,
-			GifteeID:  gifteeID,
-			FromSanta: dbMessage.FromSanta,
-			Content:   incomingMsg.Content, // Отправляем расшифрованный текст
-			ReadAt:    dbMessage.ReadAt,
-			CreatedAt: dbMessage.CreatedAt,
-		}
+	c.sendEvent(AckEvent{
+		Type:      EventAck,
+		ClientID:  event.ClientID,
+		ID:        dbMessage.ID,
+		CreatedAt: dbMessage.CreatedAt,
+	})
 
-		c.hub.broadcast <- &BroadcastMessage{
-			GroupID:  c.groupID,
-			SantaID:  santaID,
-			GifteeID: gifteeID,
-			Message:  chatMsg,
-		}
+	// Отправляем сообщение всем участникам этой пары (в оригинальном виде)
+	chatMsg := c.hub.chatMessage(&dbMessage, event.Content) // Отправляем расшифрованный текст
+
+	c.hub.Broadcast(c.groupID, chatMsg)
+
+	// У собеседника выросло число непрочитанных
+	recipient := gifteeID
+	if !fromSanta {
+		recipient = santaID
 	}
+	c.hub.pushUnread(c.groupID, recipient)
+}
+
+// handleTyping пересылает индикатор набора только собеседнику по паре
+func (c *Client) handleTyping(event IncomingEvent) {
+	santaID, gifteeID, fromSanta, ok := c.counterpart(event.Role)
+	if !ok {
+		return
+	}
+
+	// Собеседник видит, в каком из своих чатов идет набор: получатель - в чате с дарителем и наоборот
+	recipient, chat := gifteeID, ChatWithSanta
+	if !fromSanta {
+		recipient, chat = santaID, ChatWithGiftee
+	}
+
+	c.hub.NotifyMember(c.groupID, recipient, TypingEvent{
+		Type:   EventTyping,
+		Chat:   chat,
+		Typing: event.Typing,
+	})
+}
+
+// sendEvent кладет событие в очередь клиента; клиент с переполненной очередью отключается
+func (c *Client) sendEvent(v interface{}) {
+	if !c.trySend(mustMarshal(v)) {
+		log.Printf("Send buffer full for member %s, disconnecting", c.memberID)
+		c.close()
+	}
+}
+
+// sendError сообщает клиенту, что событие отклонено
+func (c *Client) sendError(clientID, message string) {
+	c.sendEvent(ErrorEvent{Type: EventError, ClientID: clientID, Error: message})
 }
 
 // writePump отправляет сообщения клиенту
@@ -254,12 +452,15 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case <-c.done:
+			// Отключение: клиент ушел или не успевает читать (переподключится и догрузит историю)
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "disconnected"))
+			return
+
+		case message := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
 
 			w, err := c.conn.NextWriter(websocket.TextMessage)
 			if err != nil {
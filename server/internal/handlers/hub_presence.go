@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Присутствие участников на всех экземплярах API
+const (
+	presenceHeartbeat = 15 * time.Second      // Как часто экземпляр сообщает, что он жив
+	presenceTimeout   = 3 * presenceHeartbeat // Экземпляр без heartbeat считается упавшим, его участники - не в сети
+)
+
+// Виды событий присутствия в pubsub
+const (
+	envelopePresence  = "presence"      // У участника появилось первое или закрылось последнее соединение с экземпляром
+	envelopeHeartbeat = "heartbeat"     // Экземпляр жив
+	envelopeSync      = "presence_sync" // Новый экземпляр просит остальных повторить, кто к ним подключен
+)
+
+// presenceKey - участник розыгрыша
+type presenceKey struct {
+	groupID  uuid.UUID
+	memberID uuid.UUID
+}
+
+// memberPresence - на каких экземплярах API у участника есть соединения
+type memberPresence struct {
+	santaID   uuid.UUID
+	gifteeID  uuid.UUID
+	instances map[uuid.UUID]bool
+}
+
+// presenceState - присутствие по событиям всех экземпляров (включая этот).
+// Все экземпляры получают события в одном порядке, поэтому приходят к одному и тому же состоянию.
+type presenceState struct {
+	mu        sync.Mutex
+	members   map[presenceKey]*memberPresence
+	instances map[uuid.UUID]time.Time // Экземпляр -> когда о нем было слышно последний раз
+}
+
+// outbox - очередь публикаций хаба. События кладутся в нее под блокировкой комнаты и публикуются
+// одной горутиной, поэтому подключение и отключение участника уходят в pubsub в том порядке,
+// в котором они произошли.
+type outbox struct {
+	mu    sync.Mutex
+	queue []hubEnvelope
+	wake  chan struct{}
+}
+
+func newOutbox() *outbox {
+	return &outbox{wake: make(chan struct{}, 1)}
+}
+
+// push добавляет событие в очередь без ожидания
+func (o *outbox) push(env hubEnvelope) {
+	o.mu.Lock()
+	o.queue = append(o.queue, env)
+	o.mu.Unlock()
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take забирает все накопившиеся события
+func (o *outbox) take() []hubEnvelope {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	queue := o.queue
+	o.queue = nil
+	return queue
+}
+
+// runOutbox публикует события из очереди по порядку и рассылает heartbeat экземпляра
+func (h *Hub) runOutbox(ctx context.Context) {
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.outbox.wake:
+			for _, env := range h.outbox.take() {
+				h.publish(env)
+			}
+		case now := <-ticker.C:
+			h.publish(hubEnvelope{Kind: envelopeHeartbeat, Instance: h.instanceID})
+			h.expireInstances(now)
+		}
+	}
+}
+
+// announcePresence ставит в очередь публикации смену присутствия участника на этом экземпляре.
+// Вызывается под блокировкой комнаты, чтобы порядок событий совпадал с порядком подключений.
+func (h *Hub) announcePresence(client *Client, online bool) {
+	h.outbox.push(hubEnvelope{
+		Kind:     envelopePresence,
+		Instance: h.instanceID,
+		GroupID:  client.groupID,
+		MemberID: client.memberID,
+		SantaID:  client.santaID,
+		GifteeID: client.gifteeID,
+		Online:   online,
+	})
+}
+
+// announceLocalPresence повторяет для нового экземпляра, кто подключен к этому
+func (h *Hub) announceLocalPresence() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range h.rooms {
+		r.mu.RLock()
+		for _, tabs := range r.members {
+			for client := range tabs {
+				h.announcePresence(client, true)
+				break
+			}
+		}
+		r.mu.RUnlock()
+	}
+}
+
+// memberOnline - у участника есть соединение с этим или другим экземпляром API
+func (h *Hub) memberOnline(groupID, memberID uuid.UUID) bool {
+	if h.isOnline(groupID, memberID) {
+		return true
+	}
+
+	h.presence.mu.Lock()
+	defer h.presence.mu.Unlock()
+	p := h.presence.members[presenceKey{groupID, memberID}]
+	return p != nil && len(p.instances) > 0
+}
+
+// applyPresence учитывает событие присутствия; если участник появился в сети или пропал со всех
+// экземпляров, его дарителю и получателю на этом экземпляре уходит PresenceEvent
+func (h *Hub) applyPresence(env hubEnvelope) {
+	key := presenceKey{env.GroupID, env.MemberID}
+
+	h.presence.mu.Lock()
+	h.presence.instances[env.Instance] = time.Now()
+	p := h.presence.members[key]
+	if p == nil {
+		p = &memberPresence{santaID: env.SantaID, gifteeID: env.GifteeID, instances: make(map[uuid.UUID]bool)}
+		h.presence.members[key] = p
+	}
+	wasOnline := len(p.instances) > 0
+	if env.Online {
+		p.instances[env.Instance] = true
+	} else {
+		delete(p.instances, env.Instance)
+	}
+	online := len(p.instances) > 0
+	if !online {
+		delete(h.presence.members, key)
+	}
+	h.presence.mu.Unlock()
+
+	if online != wasOnline {
+		h.deliverPresence(env.GroupID, p, online)
+	}
+}
+
+// expireInstances снимает присутствие участников экземпляров, от которых давно нет heartbeat
+func (h *Hub) expireInstances(now time.Time) {
+	type change struct {
+		groupID uuid.UUID
+		p       *memberPresence
+	}
+	var offline []change
+
+	h.presence.mu.Lock()
+	for instance, seen := range h.presence.instances {
+		if instance == h.instanceID || now.Sub(seen) < presenceTimeout {
+			continue
+		}
+		delete(h.presence.instances, instance)
+		log.Printf("Chat instance %s stopped sending heartbeats", instance)
+
+		for key, p := range h.presence.members {
+			if !p.instances[instance] {
+				continue
+			}
+			delete(p.instances, instance)
+			if len(p.instances) == 0 {
+				delete(h.presence.members, key)
+				offline = append(offline, change{key.groupID, p})
+			}
+		}
+	}
+	h.presence.mu.Unlock()
+
+	for _, c := range offline {
+		h.deliverPresence(c.groupID, c.p, false)
+	}
+}
+
+// resyncPresence пересобирает присутствие после переподключения к pubsub. События за время обрыва
+// потеряны, поэтому известное состояние сбрасывается: участники, подключенные только к другим
+// экземплярам, до их повторного объявления считаются не в сети. Этот экземпляр повторяет, кто
+// подключен к нему, а остальных просит о том же через envelopeSync.
+func (h *Hub) resyncPresence() {
+	h.presence.mu.Lock()
+	members := h.presence.members
+	h.presence.members = make(map[presenceKey]*memberPresence)
+	h.presence.instances = make(map[uuid.UUID]time.Time)
+	h.presence.mu.Unlock()
+
+	for key, p := range members {
+		if len(p.instances) > 0 && !h.isOnline(key.groupID, key.memberID) {
+			h.deliverPresence(key.groupID, p, false)
+		}
+	}
+
+	h.announceLocalPresence()
+	h.outbox.push(hubEnvelope{Kind: envelopeSync, Instance: h.instanceID})
+}
+
+// deliverPresence сообщает дарителю и получателю участника, что он подключился или отключился.
+// Получатель видит "даритель в сети", даритель - "получатель в сети", без ID участников.
+func (h *Hub) deliverPresence(groupID uuid.UUID, p *memberPresence, online bool) {
+	if p.gifteeID != uuid.Nil {
+		h.deliver(groupID, mustMarshal(PresenceEvent{Type: EventPresence, Chat: ChatWithSanta, Online: online}), p.gifteeID)
+	}
+	if p.santaID != uuid.Nil {
+		h.deliver(groupID, mustMarshal(PresenceEvent{Type: EventPresence, Chat: ChatWithGiftee, Online: online}), p.santaID)
+	}
+}
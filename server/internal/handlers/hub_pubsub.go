@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secret-santa/internal/crypto"
+	"secret-santa/internal/models"
+	"secret-santa/internal/pubsub"
+)
+
+// hubChannel - канал pubsub для событий чата
+const hubChannel = "chat_events"
+
+// publishTimeout - сколько ждать публикации события
+const publishTimeout = 5 * time.Second
+
+// Загрузка сообщений чата для рассылки: каждое сообщение читается из БД, поэтому это делают
+// несколько горутин, а не горутина подписки. Пара всегда попадает к одному загрузчику -
+// новое сообщение, правка и удаление доходят до нее по порядку.
+const (
+	messageLoaders      = 8
+	messageLoaderBuffer = 256
+)
+
+// Виды событий хаба в pubsub
+const (
+	envelopeMessage = "message" // Новое сообщение пары
+	envelopeNotify  = "notify"  // Событие для одного участника
+)
+
+// hubEnvelope - событие хаба в pubsub. Сообщения чата передаются только по ID:
+// текст не уходит в NOTIFY открытым, а каждый экземпляр читает его из БД, если у него есть клиенты пары.
+type hubEnvelope struct {
+	Kind      string          `json:"kind"`
+	Event     string          `json:"event,omitempty"` // Для envelopeMessage: EventMessage, EventEdit или EventDelete
+	GroupID   uuid.UUID       `json:"group_id"`
+	SantaID   uuid.UUID       `json:"santa_id,omitempty"`
+	GifteeID  uuid.UUID       `json:"giftee_id,omitempty"`
+	MessageID uuid.UUID       `json:"message_id,omitempty"`
+	MemberID  uuid.UUID       `json:"member_id,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Instance  uuid.UUID       `json:"instance,omitempty"` // Для событий присутствия: экземпляр API
+	Online    bool            `json:"online,omitempty"`
+}
+
+// Subscribe подписывает хаб на события всех экземпляров API
+func (h *Hub) Subscribe(ctx context.Context) error {
+	events, err := h.ps.Subscribe(ctx, hubChannel)
+	if err != nil {
+		return err
+	}
+	go h.consume(events)
+	go h.runOutbox(ctx)
+
+	// Узнаем, кто уже подключен к другим экземплярам
+	h.outbox.push(hubEnvelope{Kind: envelopeSync, Instance: h.instanceID})
+	return nil
+}
+
+// Broadcast рассылает сообщение обоим участникам пары на всех экземплярах API
+func (h *Hub) Broadcast(groupID uuid.UUID, msg *ChatMessage) {
+	h.broadcastMessage(groupID, msg, EventMessage)
+}
+
+// broadcastMessage рассылает паре актуальное состояние сообщения как событие event
+// (новое, отредактированное или удаленное)
+func (h *Hub) broadcastMessage(groupID uuid.UUID, msg *ChatMessage, event string) {
+	h.publish(hubEnvelope{
+		Kind:      envelopeMessage,
+		Event:     event,
+		GroupID:   groupID,
+		SantaID:   msg.SantaID,
+		GifteeID:  msg.GifteeID,
+		MessageID: msg.ID,
+	})
+}
+
+// NotifyMember отправляет событие участнику, если он сейчас подключен (к любому экземпляру API)
+func (h *Hub) NotifyMember(groupID, memberID uuid.UUID, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to marshal notification: %v", err)
+		return
+	}
+
+	h.publish(hubEnvelope{
+		Kind:     envelopeNotify,
+		GroupID:  groupID,
+		MemberID: memberID,
+		Payload:  data,
+	})
+}
+
+func (h *Hub) publish(env hubEnvelope) {
+	data, err := json.Marshal(env)
+	if err != nil {
+		log.Printf("Failed to marshal hub event: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := h.ps.Publish(ctx, hubChannel, data); err != nil {
+		log.Printf("Failed to publish hub event %s: %v", env.Kind, err)
+	}
+}
+
+// consume разбирает события pubsub и раздает их локальным клиентам
+func (h *Hub) consume(events <-chan []byte) {
+	loaders := make([]chan hubEnvelope, messageLoaders)
+	for i := range loaders {
+		loaders[i] = make(chan hubEnvelope, messageLoaderBuffer)
+		go h.loadMessages(loaders[i])
+	}
+	defer func() {
+		for _, ch := range loaders {
+			close(ch)
+		}
+	}()
+
+	for data := range events {
+		if pubsub.IsReconnected(data) {
+			log.Println("Hub pubsub subscription reconnected, resyncing presence")
+			h.resyncPresence()
+			continue
+		}
+
+		var env hubEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			log.Printf("Invalid hub event: %v", err)
+			continue
+		}
+
+		switch env.Kind {
+		case envelopeMessage:
+			if !h.hasLocalClients(env.GroupID, env.SantaID, env.GifteeID) {
+				continue
+			}
+			loaders[messageLoader(env.SantaID)] <- env
+
+		case envelopeNotify:
+			h.deliver(env.GroupID, env.Payload, env.MemberID)
+
+		case envelopePresence:
+			h.applyPresence(env)
+
+		case envelopeHeartbeat:
+			h.presence.mu.Lock()
+			h.presence.instances[env.Instance] = time.Now()
+			h.presence.mu.Unlock()
+
+		case envelopeSync:
+			if env.Instance != h.instanceID {
+				h.announceLocalPresence()
+			}
+		}
+	}
+	log.Println("Hub pubsub subscription closed")
+}
+
+// messageLoader - номер загрузчика для пары (у дарителя в розыгрыше одна пара)
+func messageLoader(santaID uuid.UUID) int {
+	return int(binary.BigEndian.Uint32(santaID[:4]) % messageLoaders)
+}
+
+// loadMessages читает сообщения из очереди загрузчика и рассылает их паре
+func (h *Hub) loadMessages(queue <-chan hubEnvelope) {
+	for env := range queue {
+		msg, err := h.loadMessage(env.MessageID)
+		if err != nil {
+			log.Printf("Failed to load message %s: %v", env.MessageID, err)
+			continue
+		}
+		event := env.Event
+		if event == "" {
+			event = EventMessage
+		}
+		// Отправляем сообщение только участникам этой пары (santa и giftee)
+		h.deliver(env.GroupID, mustMarshal(MessageEvent{Type: event, ChatMessage: msg}), env.SantaID, env.GifteeID)
+	}
+}
+
+// hasLocalClients - к этому экземпляру подключен кто-то из участников
+func (h *Hub) hasLocalClients(groupID uuid.UUID, memberIDs ...uuid.UUID) bool {
+	for _, memberID := range memberIDs {
+		if h.isOnline(groupID, memberID) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadChatMessage читает и расшифровывает сообщение для рассылки
+func (h *Hub) loadChatMessage(id uuid.UUID) (*ChatMessage, error) {
+	var msg models.Message
+	if err := h.db.First(&msg, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	// У удаленного сообщения текста нет
+	var content string
+	if msg.DeletedAt == nil {
+		var err error
+		if content, err = crypto.Decrypt(msg.Content, h.encryptionKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return h.chatMessage(&msg, content), nil
+}
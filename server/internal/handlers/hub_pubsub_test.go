@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secret-santa/internal/pubsub"
+)
+
+// testEvent - событие, которое хаб положил в очередь клиента
+type testEvent struct {
+	Type       string      `json:"type"`
+	Chat       string      `json:"chat"`
+	Online     bool        `json:"online"`
+	Typing     bool        `json:"typing"`
+	ID         uuid.UUID   `json:"id"`
+	Content    string      `json:"content"`
+	MessageIDs []uuid.UUID `json:"message_ids"`
+}
+
+// newTestHub создает хаб поверх общего pubsub (несколько хабов - несколько экземпляров API)
+func newTestHub(t testing.TB, ps pubsub.PubSub) *Hub {
+	t.Helper()
+	h := NewHub(nil, nil, ps)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := h.Subscribe(ctx); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	return h
+}
+
+// newTestClient регистрирует в хабе клиента без WebSocket: события копятся в его очереди
+func newTestClient(h *Hub, groupID, memberID, santaID, gifteeID uuid.UUID) *Client {
+	c := &Client{
+		hub:      h,
+		send:     make(chan []byte, sendBufferSize),
+		done:     make(chan struct{}),
+		userID:   uuid.New(),
+		memberID: memberID,
+		groupID:  groupID,
+		santaID:  santaID,
+		gifteeID: gifteeID,
+	}
+	h.register(c)
+	return c
+}
+
+// nextEvent ждет следующее событие клиента
+func nextEvent(t *testing.T, c *Client) testEvent {
+	t.Helper()
+	select {
+	case data := <-c.send:
+		var e testEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			t.Fatalf("invalid event %s: %v", data, err)
+		}
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+	}
+	return testEvent{}
+}
+
+// expectNoEvent проверяет, что клиенту ничего не пришло
+func expectNoEvent(t *testing.T, c *Client) {
+	t.Helper()
+	select {
+	case data := <-c.send:
+		t.Errorf("unexpected event %s", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// expectPresence ждет событие присутствия собеседника
+func expectPresence(t *testing.T, c *Client, chat string, online bool) {
+	t.Helper()
+	e := nextEvent(t, c)
+	if e.Type != EventPresence || e.Chat != chat || e.Online != online {
+		t.Fatalf("event = %+v, want presence chat=%s online=%v", e, chat, online)
+	}
+}
+
+// eventually ждет выполнения условия
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitKnown ждет, пока событие о подключении участника дойдет до хабов через pubsub
+func waitKnown(t *testing.T, groupID, memberID uuid.UUID, hubs ...*Hub) {
+	t.Helper()
+	for _, h := range hubs {
+		eventually(t, func() bool {
+			h.presence.mu.Lock()
+			defer h.presence.mu.Unlock()
+			return h.presence.members[presenceKey{groupID, memberID}] != nil
+		})
+	}
+}
+
+func TestConsumeNotify(t *testing.T) {
+	h := newTestHub(t, pubsub.NewMemory())
+	groupID, memberID := uuid.New(), uuid.New()
+
+	member := newTestClient(h, groupID, memberID, uuid.Nil, uuid.Nil)
+	other := newTestClient(h, groupID, uuid.New(), uuid.Nil, uuid.Nil)
+
+	h.NotifyMember(groupID, memberID, TypingEvent{Type: EventTyping, Chat: ChatWithSanta, Typing: true})
+
+	if e := nextEvent(t, member); e.Type != EventTyping || e.Chat != ChatWithSanta || !e.Typing {
+		t.Errorf("event = %+v, want typing", e)
+	}
+	expectNoEvent(t, other)
+}
+
+func TestConsumeMessage(t *testing.T) {
+	h := newTestHub(t, pubsub.NewMemory())
+	groupID, santaID, gifteeID := uuid.New(), uuid.New(), uuid.New()
+	messageID := uuid.New()
+
+	var loads atomic.Int32
+	h.loadMessage = func(id uuid.UUID) (*ChatMessage, error) {
+		loads.Add(1)
+		return &ChatMessage{ID: id, SantaID: santaID, GifteeID: gifteeID, FromSanta: true, Content: "Hi"}, nil
+	}
+
+	// Чужой розыгрыш: сообщение пары, у которой нет клиентов на этом экземпляре, не читается из БД
+	h.Broadcast(uuid.New(), &ChatMessage{ID: uuid.New(), SantaID: uuid.New(), GifteeID: uuid.New()})
+
+	santa := newTestClient(h, groupID, santaID, uuid.Nil, gifteeID)
+	expectPresence(t, santa, ChatWithGiftee, false)
+	waitKnown(t, groupID, santaID, h)
+	giftee := newTestClient(h, groupID, gifteeID, santaID, uuid.Nil)
+	expectPresence(t, giftee, ChatWithSanta, true)
+	expectPresence(t, santa, ChatWithGiftee, true)
+	stranger := newTestClient(h, groupID, uuid.New(), uuid.Nil, uuid.Nil)
+
+	h.Broadcast(groupID, &ChatMessage{ID: messageID, SantaID: santaID, GifteeID: gifteeID})
+	for _, c := range []*Client{santa, giftee} {
+		if e := nextEvent(t, c); e.Type != EventMessage || e.ID != messageID || e.Content != "Hi" {
+			t.Errorf("event = %+v, want message %s", e, messageID)
+		}
+	}
+
+	h.broadcastMessage(groupID, &ChatMessage{ID: messageID, SantaID: santaID, GifteeID: gifteeID}, EventEdit)
+	if e := nextEvent(t, giftee); e.Type != EventEdit || e.ID != messageID {
+		t.Errorf("event = %+v, want edit %s", e, messageID)
+	}
+
+	expectNoEvent(t, stranger)
+	if n := loads.Load(); n != 2 {
+		t.Errorf("messages loaded %d times, want 2", n)
+	}
+}
+
+func TestPresenceAcrossInstances(t *testing.T) {
+	ps := pubsub.NewMemory()
+	a, b := newTestHub(t, ps), newTestHub(t, ps)
+	groupID, santaID, gifteeID := uuid.New(), uuid.New(), uuid.New()
+
+	santa := newTestClient(b, groupID, santaID, uuid.Nil, gifteeID)
+	expectPresence(t, santa, ChatWithGiftee, false)
+	waitKnown(t, groupID, santaID, a, b)
+
+	// Получатель подключается к другому экземпляру - даритель видит его в сети
+	tabA := newTestClient(a, groupID, gifteeID, santaID, uuid.Nil)
+	expectPresence(t, tabA, ChatWithSanta, true)
+	expectPresence(t, santa, ChatWithGiftee, true)
+
+	// Вторая вкладка на экземпляре дарителя: он уже в сети, событий нет
+	tabB := newTestClient(b, groupID, gifteeID, santaID, uuid.Nil)
+	expectPresence(t, tabB, ChatWithSanta, true)
+	expectNoEvent(t, santa)
+
+	// Новая вкладка дарителя на первом экземпляре сразу знает, что получатель в сети
+	santaTabA := newTestClient(a, groupID, santaID, uuid.Nil, gifteeID)
+	expectPresence(t, santaTabA, ChatWithGiftee, true)
+
+	// Закрыта вкладка на одном экземпляре - получатель еще в сети на другом
+	a.unregister(tabA)
+	expectNoEvent(t, santa)
+
+	b.unregister(tabB)
+	expectPresence(t, santa, ChatWithGiftee, false)
+	expectPresence(t, santaTabA, ChatWithGiftee, false)
+}
+
+func TestPresenceOrdered(t *testing.T) {
+	h := newTestHub(t, pubsub.NewMemory())
+	groupID, santaID, gifteeID := uuid.New(), uuid.New(), uuid.New()
+
+	santa := newTestClient(h, groupID, santaID, uuid.Nil, gifteeID)
+	expectPresence(t, santa, ChatWithGiftee, false)
+
+	// Частые переподключения: события приходят по порядку, последним - "не в сети"
+	const cycles = 50
+	for i := 0; i < cycles; i++ {
+		h.unregister(newTestClient(h, groupID, gifteeID, santaID, uuid.Nil))
+	}
+	for i := 0; i < cycles; i++ {
+		expectPresence(t, santa, ChatWithGiftee, true)
+		expectPresence(t, santa, ChatWithGiftee, false)
+	}
+	expectNoEvent(t, santa)
+}
+
+func TestPresenceSyncForNewInstance(t *testing.T) {
+	ps := pubsub.NewMemory()
+	a := newTestHub(t, ps)
+	groupID, santaID, gifteeID := uuid.New(), uuid.New(), uuid.New()
+	newTestClient(a, groupID, gifteeID, santaID, uuid.Nil)
+
+	// Экземпляр, запущенный позже, узнает о подключениях к остальным
+	b := newTestHub(t, ps)
+	eventually(t, func() bool { return b.memberOnline(groupID, gifteeID) })
+}
+
+func TestPresenceExpiresSilentInstance(t *testing.T) {
+	ps := pubsub.NewMemory()
+	h := newTestHub(t, ps)
+	groupID, santaID, gifteeID := uuid.New(), uuid.New(), uuid.New()
+
+	santa := newTestClient(h, groupID, santaID, uuid.Nil, gifteeID)
+	expectPresence(t, santa, ChatWithGiftee, false)
+
+	// Получатель подключен к экземпляру, который потом пропадает без событий об отключении
+	data, _ := json.Marshal(hubEnvelope{
+		Kind:     envelopePresence,
+		Instance: uuid.New(),
+		GroupID:  groupID,
+		MemberID: gifteeID,
+		SantaID:  santaID,
+		Online:   true,
+	})
+	if err := ps.Publish(context.Background(), hubChannel, data); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	expectPresence(t, santa, ChatWithGiftee, true)
+
+	h.expireInstances(time.Now())
+	expectNoEvent(t, santa)
+
+	h.expireInstances(time.Now().Add(presenceTimeout + time.Second))
+	expectPresence(t, santa, ChatWithGiftee, false)
+	if h.memberOnline(groupID, gifteeID) {
+		t.Error("member of a silent instance is still online")
+	}
+}
+
+func TestPresenceResyncAfterReconnect(t *testing.T) {
+	ps := pubsub.NewMemory()
+	a, b := newTestHub(t, ps), newTestHub(t, ps)
+	groupID, santaID, gifteeID := uuid.New(), uuid.New(), uuid.New()
+
+	santa := newTestClient(a, groupID, santaID, uuid.Nil, gifteeID)
+	expectPresence(t, santa, ChatWithGiftee, false)
+	waitKnown(t, groupID, santaID, a, b)
+	giftee := newTestClient(b, groupID, gifteeID, santaID, uuid.Nil)
+	expectPresence(t, giftee, ChatWithSanta, true)
+	expectPresence(t, santa, ChatWithGiftee, true)
+
+	// Участник экземпляра, чье "не в сети" потерялось во время обрыва
+	lost := uuid.New()
+	data, _ := json.Marshal(hubEnvelope{
+		Kind:     envelopePresence,
+		Instance: uuid.New(),
+		GroupID:  groupID,
+		MemberID: lost,
+		Online:   true,
+	})
+	if err := ps.Publish(context.Background(), hubChannel, data); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	waitKnown(t, groupID, lost, a, b)
+
+	// После переподключения собеседник на другом экземпляре пропадает до повторного объявления
+	ps.Interrupt(hubChannel)
+	expectPresence(t, santa, ChatWithGiftee, false)
+	expectPresence(t, santa, ChatWithGiftee, true)
+	expectPresence(t, giftee, ChatWithSanta, false)
+	expectPresence(t, giftee, ChatWithSanta, true)
+	expectNoEvent(t, santa)
+
+	for _, h := range []*Hub{a, b} {
+		eventually(t, func() bool {
+			return h.memberOnline(groupID, santaID) && h.memberOnline(groupID, gifteeID)
+		})
+		if h.memberOnline(groupID, lost) {
+			t.Error("member nobody announced again is still online")
+		}
+	}
+}
+
+func TestReadEventFitsNotify(t *testing.T) {
+	ids := make([]uuid.UUID, readEventChunk)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	payload, _ := json.Marshal(ReadEvent{Type: EventRead, Chat: ChatWithGiftee, MessageIDs: ids, ReadAt: time.Now()})
+	data, _ := json.Marshal(hubEnvelope{Kind: envelopeNotify, GroupID: uuid.New(), MemberID: uuid.New(), Payload: payload})
+
+	if len(data) > pubsub.MaxPostgresPayload {
+		t.Errorf("read receipt of %d messages is %d bytes, NOTIFY limit is %d", readEventChunk, len(data), pubsub.MaxPostgresPayload)
+	}
+}
+
+func TestChunkIDs(t *testing.T) {
+	ids := make([]uuid.UUID, 2*readEventChunk+1)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+
+	tests := []struct {
+		n    int
+		want []int
+	}{
+		{0, nil},
+		{1, []int{1}},
+		{readEventChunk, []int{readEventChunk}},
+		{readEventChunk + 1, []int{readEventChunk, 1}},
+		{2*readEventChunk + 1, []int{readEventChunk, readEventChunk, 1}},
+	}
+
+	for _, tt := range tests {
+		chunks := chunkIDs(ids[:tt.n], readEventChunk)
+		if len(chunks) != len(tt.want) {
+			t.Fatalf("chunkIDs(%d) = %d chunks, want %d", tt.n, len(chunks), len(tt.want))
+		}
+		next := 0
+		for i, chunk := range chunks {
+			if len(chunk) != tt.want[i] {
+				t.Errorf("chunkIDs(%d)[%d] has %d IDs, want %d", tt.n, i, len(chunk), tt.want[i])
+			}
+			for _, id := range chunk {
+				if id != ids[next] {
+					t.Fatalf("chunkIDs(%d) lost the order of IDs", tt.n)
+				}
+				next++
+			}
+		}
+	}
+}
+
+func TestMarkReadChunksReceipts(t *testing.T) {
+	h := newTestHub(t, pubsub.NewMemory())
+	groupID, memberID := uuid.New(), uuid.New()
+	member := newTestClient(h, groupID, memberID, uuid.Nil, uuid.Nil)
+
+	ids := make([]uuid.UUID, MaxReadBatch)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	now := time.Now()
+	for _, chunk := range chunkIDs(ids, readEventChunk) {
+		h.NotifyMember(groupID, memberID, ReadEvent{Type: EventRead, Chat: ChatWithGiftee, MessageIDs: chunk, ReadAt: now})
+	}
+
+	// Квитанция о максимальной пачке доходит целиком
+	var got []uuid.UUID
+	for len(got) < len(ids) {
+		e := nextEvent(t, member)
+		if e.Type != EventRead {
+			t.Fatalf("event = %+v, want read", e)
+		}
+		got = append(got, e.MessageIDs...)
+	}
+	for i := range ids {
+		if got[i] != ids[i] {
+			t.Fatalf("receipt IDs out of order at %d", i)
+		}
+	}
+}
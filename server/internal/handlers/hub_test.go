@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"secret-santa/internal/pubsub"
+)
+
+func TestMain(m *testing.M) {
+	// Хаб пишет в лог каждое подключение - в тестах это только шум
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testRoom - розыгрыш с участниками по кругу: i дарит i+1
+type testRoom struct {
+	groupID uuid.UUID
+	members []uuid.UUID
+}
+
+func newTestRooms(rooms, members int) []testRoom {
+	result := make([]testRoom, rooms)
+	for i := range result {
+		result[i].groupID = uuid.New()
+		for j := 0; j < members; j++ {
+			result[i].members = append(result[i].members, uuid.New())
+		}
+	}
+	return result
+}
+
+// pair возвращает дарителя и получателя i-го участника
+func (r testRoom) pair(i int) (santaID, gifteeID uuid.UUID) {
+	n := len(r.members)
+	return r.members[(i+n-1)%n], r.members[(i+1)%n]
+}
+
+// attachDraining подключает клиента, чьи события вычитывает отдельная горутина (как writePump)
+func attachDraining(h *Hub, r testRoom, i int, received *atomic.Int64) *Client {
+	santaID, gifteeID := r.pair(i)
+	c := newTestClient(h, r.groupID, r.members[i], santaID, gifteeID)
+	go func() {
+		for {
+			select {
+			case <-c.send:
+				received.Add(1)
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// waitReceived ждет, пока клиенты получат не меньше want событий
+func waitReceived(tb testing.TB, received *atomic.Int64, want int64) {
+	deadline := time.Now().Add(30 * time.Second)
+	for received.Load() < want {
+		if time.Now().After(deadline) {
+			tb.Fatalf("received %d events, want %d", received.Load(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHubConcurrentRegisterDeliverUnregister(t *testing.T) {
+	h := newTestHub(t, pubsub.NewMemory())
+	rooms := newTestRooms(10, 8)
+
+	var received atomic.Int64
+	var wg sync.WaitGroup
+	for _, r := range rooms {
+		for i := range r.members {
+			wg.Add(1)
+			go func(r testRoom, i int) {
+				defer wg.Done()
+				// Переподключения вперемешку с рассылками в ту же комнату
+				for k := 0; k < 20; k++ {
+					c := attachDraining(h, r, i, &received)
+					h.deliver(r.groupID, []byte(`{"type":"bench"}`), r.members...)
+					h.NotifyMember(r.groupID, r.members[(i+k)%len(r.members)], TypingEvent{Type: EventTyping})
+					h.Stats()
+					h.unregister(c)
+				}
+			}(r, i)
+		}
+	}
+	wg.Wait()
+
+	if rooms, clients := h.Stats(); rooms != 0 || clients != 0 {
+		t.Errorf("Stats() = %d rooms, %d clients after everyone left, want 0, 0", rooms, clients)
+	}
+
+	// Все подключения и отключения учтены в присутствии: в итоге никого нет в сети
+	eventually(t, func() bool {
+		for _, r := range rooms {
+			for _, m := range r.members {
+				if h.memberOnline(r.groupID, m) {
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
+
+func TestConsumeMessageOrderedPerPair(t *testing.T) {
+	h := newTestHub(t, pubsub.NewMemory())
+	groupID, santaID, gifteeID := uuid.New(), uuid.New(), uuid.New()
+
+	h.loadMessage = func(id uuid.UUID) (*ChatMessage, error) {
+		// Разное время чтения из БД не меняет порядок рассылки внутри пары
+		time.Sleep(time.Duration(id[0]%4) * 100 * time.Microsecond)
+		return &ChatMessage{ID: id, SantaID: santaID, GifteeID: gifteeID}, nil
+	}
+
+	giftee := newTestClient(h, groupID, gifteeID, santaID, uuid.Nil)
+	expectPresence(t, giftee, ChatWithSanta, false)
+
+	ids := make([]uuid.UUID, 50)
+	for i := range ids {
+		ids[i] = uuid.New()
+		h.Broadcast(groupID, &ChatMessage{ID: ids[i], SantaID: santaID, GifteeID: gifteeID})
+	}
+	for i := range ids {
+		if e := nextEvent(t, giftee); e.ID != ids[i] {
+			t.Fatalf("message %d = %s, want %s", i, e.ID, ids[i])
+		}
+	}
+}
+
+func TestConsumeLoadsPairsConcurrently(t *testing.T) {
+	h := newTestHub(t, pubsub.NewMemory())
+	groupID := uuid.New()
+
+	// Две пары с разными загрузчиками
+	slowSanta, fastSanta := uuid.New(), uuid.New()
+	for messageLoader(fastSanta) == messageLoader(slowSanta) {
+		fastSanta = uuid.New()
+	}
+	slowGiftee, fastGiftee := uuid.New(), uuid.New()
+
+	release := make(chan struct{})
+	defer close(release)
+	slowID := uuid.New()
+	h.loadMessage = func(id uuid.UUID) (*ChatMessage, error) {
+		if id == slowID {
+			<-release // Медленный запрос к БД
+			return &ChatMessage{ID: id, SantaID: slowSanta, GifteeID: slowGiftee}, nil
+		}
+		return &ChatMessage{ID: id, SantaID: fastSanta, GifteeID: fastGiftee}, nil
+	}
+
+	fast := newTestClient(h, groupID, fastGiftee, fastSanta, uuid.Nil)
+	expectPresence(t, fast, ChatWithSanta, false)
+	newTestClient(h, groupID, slowGiftee, slowSanta, uuid.Nil)
+
+	h.Broadcast(groupID, &ChatMessage{ID: slowID, SantaID: slowSanta, GifteeID: slowGiftee})
+	fastID := uuid.New()
+	h.Broadcast(groupID, &ChatMessage{ID: fastID, SantaID: fastSanta, GifteeID: fastGiftee})
+
+	// Сообщение другой пары не ждет медленную загрузку
+	if e := nextEvent(t, fast); e.ID != fastID {
+		t.Errorf("event = %+v, want message %s", e, fastID)
+	}
+}
+
+// BenchmarkDeliver - точечная рассылка участнику на этом экземпляре (без pubsub)
+func BenchmarkDeliver(b *testing.B) {
+	for _, size := range []struct{ rooms, members int }{{10, 10}, {1000, 20}} {
+		b.Run(fmt.Sprintf("rooms=%d/members=%d", size.rooms, size.members), func(b *testing.B) {
+			h := NewHub(nil, nil, pubsub.NewMemory())
+			rooms := newTestRooms(size.rooms, size.members)
+			var received atomic.Int64
+			for _, r := range rooms {
+				for i := range r.members {
+					defer h.unregister(attachDraining(h, r, i, &received))
+				}
+			}
+			data := []byte(`{"type":"bench"}`)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r := rooms[i%len(rooms)]
+				h.deliver(r.groupID, data, r.members[i%len(r.members)])
+			}
+			waitReceived(b, &received, int64(b.N))
+		})
+	}
+}
+
+// BenchmarkNotifyMember - событие участнику через pubsub до очереди клиента
+func BenchmarkNotifyMember(b *testing.B) {
+	h := newTestHub(b, pubsub.NewMemory())
+	rooms := newTestRooms(100, 20)
+	var received atomic.Int64
+	for _, r := range rooms {
+		for i := range r.members {
+			defer h.unregister(attachDraining(h, r, i, &received))
+		}
+	}
+	// Присутствие при подключении тоже приходит событиями - считаем только рассылку
+	time.Sleep(100 * time.Millisecond)
+	received.Store(0)
+
+	event := TypingEvent{Type: EventTyping, Chat: ChatWithSanta, Typing: true}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := rooms[i%len(rooms)]
+		h.NotifyMember(r.groupID, r.members[i%len(r.members)], event)
+	}
+	waitReceived(b, &received, int64(b.N))
+}
+
+// BenchmarkRegisterUnregister - подключение и отключение вкладок из многих горутин
+func BenchmarkRegisterUnregister(b *testing.B) {
+	h := newTestHub(b, pubsub.NewMemory())
+	rooms := newTestRooms(100, 20)
+
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := int(next.Add(1))
+			r := rooms[n%len(rooms)]
+			santaID, gifteeID := r.pair(n % len(r.members))
+			c := &Client{
+				hub:      h,
+				send:     make(chan []byte, sendBufferSize),
+				done:     make(chan struct{}),
+				memberID: r.members[n%len(r.members)],
+				groupID:  r.groupID,
+				santaID:  santaID,
+				gifteeID: gifteeID,
+			}
+			h.register(c)
+			h.unregister(c)
+		}
+	})
+}
+
+// BenchmarkConsumeMessage - сообщения чата через pubsub: каждое читается из БД (здесь - задержка
+// loadMessage), загрузка разных пар идет параллельно
+func BenchmarkConsumeMessage(b *testing.B) {
+	for _, latency := range []time.Duration{0, 200 * time.Microsecond} {
+		b.Run(fmt.Sprintf("db=%s", latency), func(b *testing.B) {
+			h := newTestHub(b, pubsub.NewMemory())
+			rooms := newTestRooms(50, 10)
+
+			type pairMessage struct {
+				groupID uuid.UUID
+				msg     *ChatMessage
+			}
+			messages := make(map[uuid.UUID]*ChatMessage)
+			var pairs []pairMessage
+			for _, r := range rooms {
+				for i := range r.members {
+					_, gifteeID := r.pair(i)
+					msg := &ChatMessage{ID: uuid.New(), SantaID: r.members[i], GifteeID: gifteeID}
+					messages[msg.ID] = msg
+					pairs = append(pairs, pairMessage{r.groupID, msg})
+				}
+			}
+			h.loadMessage = func(id uuid.UUID) (*ChatMessage, error) {
+				if latency > 0 {
+					time.Sleep(latency)
+				}
+				return messages[id], nil
+			}
+
+			var received atomic.Int64
+			for _, r := range rooms {
+				for i := range r.members {
+					defer h.unregister(attachDraining(h, r, i, &received))
+				}
+			}
+			time.Sleep(100 * time.Millisecond)
+			received.Store(0)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p := pairs[i%len(pairs)]
+				h.Broadcast(p.groupID, p.msg)
+			}
+			// Сообщение получают оба участника пары
+			waitReceived(b, &received, 2*int64(b.N))
+		})
+	}
+}
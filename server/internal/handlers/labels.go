@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"secret-santa/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// parcelCodeAlphabet - символы кода посылки без похожих друг на друга (0/O, 1/I)
+const parcelCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// parcelCodeLength - длина кода посылки без префикса
+const parcelCodeLength = 6
+
+// Ошибки подготовки этикетки
+var (
+	errHandDeliveryOnly   = errors.New("giftee accepts hand delivery only")
+	errAddressNotRevealed = errors.New("giftee address is not available yet")
+	errAddressMissing     = errors.New("giftee has not filled the address")
+)
+
+// ShippingLabel - данные этикетки (без информации о дарителе)
+type ShippingLabel struct {
+	ParcelCode    string
+	Name          string
+	Phone         string
+	Lines         []string
+	Country       string
+	International bool
+}
+
+// labelsPage - данные страницы с этикетками
+type labelsPage struct {
+	Title   string
+	Labels  []ShippingLabel
+	Skipped []string // Коды посылок без этикетки (лично в руки, адрес еще скрыт)
+}
+
+var labelsTemplate = template.Must(template.New("labels").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+  body { font-family: Arial, Helvetica, sans-serif; margin: 0; }
+  .label { width: 100mm; min-height: 60mm; box-sizing: border-box; margin: 5mm; padding: 5mm;
+           border: 1px dashed #333; display: inline-block; vertical-align: top; page-break-inside: avoid; }
+  .code { font-family: monospace; font-size: 20pt; font-weight: bold; letter-spacing: 2px; }
+  .to { margin-top: 3mm; font-size: 12pt; line-height: 1.4; }
+  .name { font-weight: bold; }
+  .country { font-weight: bold; text-transform: uppercase; }
+  .skipped { margin: 5mm; font-size: 10pt; }
+  @media print { .label { border: 1px solid #000; } .skipped { display: none; } }
+</style>
+</head>
+<body>
+{{range .Labels}}<div class="label">
+  <div class="code">{{.ParcelCode}}</div>
+  <div class="to">
+    <div class="name">{{.Name}}</div>
+    {{range .Lines}}<div>{{.}}</div>{{end}}
+    {{if .Country}}<div class="country">{{.Country}}</div>{{end}}
+    {{if .Phone}}<div>{{if .International}}Tel.{{else}}Тел.{{end}} {{.Phone}}</div>{{end}}
+  </div>
+</div>
+{{end}}{{if .Skipped}}<div class="skipped">No label (hand delivery or address not yet available): {{range $i, $c := .Skipped}}{{if $i}}, {{end}}{{$c}}{{end}}</div>{{end}}
+</body>
+</html>
+`))
+
+// GetGifteeLabel - печатная этикетка для отправки подарка моему получателю
+func (h *Handler) GetGifteeLabel(c *gin.Context) {
+	santa, ok := h.drawnSanta(c)
+	if !ok {
+		return
+	}
+
+	var group models.Group
+	if err := h.DB.First(&group, santa.GroupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	var giftee models.Member
+	if err := h.DB.Preload("User").First(&giftee, *santa.GifteeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Giftee not found"})
+		return
+	}
+
+	members := []models.Member{giftee}
+	h.ensureParcelCodes(members)
+
+	label, err := buildShippingLabel(members[0], group, memberCountry(santa), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	renderLabels(c, labelsPage{Title: "Label " + label.ParcelCode, Labels: []ShippingLabel{label}})
+}
+
+// ExportLabels - все этикетки розыгрыша для организатора, который рассылает подарки централизованно.
+// Этикетки идут по кодам посылок: организатор не видит, кто кому дарит.
+func (h *Handler) ExportLabels(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var group models.Group
+	if err := h.DB.Preload("Members").Preload("Members.User").First(&group, rid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	if group.OwnerID != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only raffle owner can export labels"})
+		return
+	}
+
+	if !group.IsDrawn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Draw not yet conducted"})
+		return
+	}
+
+	// Организатор отправляет из своей страны
+	fromCountry := ""
+	for _, m := range group.Members {
+		if m.UserID == group.OwnerID {
+			fromCountry = memberCountry(m)
+			break
+		}
+	}
+
+	h.ensureParcelCodes(group.Members)
+
+	page := labelsPage{Title: group.Name + " - labels"}
+	now := time.Now()
+	for _, m := range group.Members {
+		label, err := buildShippingLabel(m, group, fromCountry, now)
+		if err != nil {
+			if m.ParcelCode != nil {
+				page.Skipped = append(page.Skipped, *m.ParcelCode)
+			}
+			continue
+		}
+		page.Labels = append(page.Labels, label)
+	}
+
+	// Порядок по коду, а не по участникам, чтобы не выдавать порядок вступления
+	sort.Slice(page.Labels, func(i, j int) bool {
+		return page.Labels[i].ParcelCode < page.Labels[j].ParcelCode
+	})
+	sort.Strings(page.Skipped)
+
+	renderLabels(c, page)
+}
+
+// buildShippingLabel собирает этикетку с учетом приватности адреса получателя.
+// Для международной отправки используются поля *En (если заполнены).
+func buildShippingLabel(giftee models.Member, group models.Group, fromCountry string, now time.Time) (ShippingLabel, error) {
+	if giftee.ParcelCode == nil {
+		return ShippingLabel{}, errors.New("parcel code is not assigned")
+	}
+	label := ShippingLabel{
+		ParcelCode: *giftee.ParcelCode,
+		Name:       giftee.User.Name,
+	}
+
+	switch giftee.AddressPrivacy {
+	case models.AddressPrivacyHandDelivery:
+		return label, errHandDeliveryOnly
+	case models.AddressPrivacyOffice:
+		if group.OfficeAddress == nil {
+			return label, errAddressMissing
+		}
+		label.Lines = strings.Split(*group.OfficeAddress, "\n")
+		return label, nil
+	case models.AddressPrivacyDelayed:
+		if at := addressAvailableAt(giftee, group); at == nil || at.After(now) {
+			return label, errAddressNotRevealed
+		}
+	}
+
+	if giftee.AddressLine1 == nil || *giftee.AddressLine1 == "" || giftee.City == nil || *giftee.City == "" {
+		return label, errAddressMissing
+	}
+
+	country := memberCountry(giftee)
+	label.International = fromCountry == "" || country == "" || !strings.EqualFold(fromCountry, country)
+	label.Country = strings.ToUpper(country)
+	if giftee.Phone != nil {
+		label.Phone = *giftee.Phone
+	}
+
+	pick := func(local, en *string) string {
+		if label.International && en != nil && *en != "" {
+			return *en
+		}
+		if local != nil {
+			return *local
+		}
+		return ""
+	}
+
+	for _, line := range []string{
+		pick(giftee.AddressLine1, giftee.AddressLine1En),
+		pick(giftee.AddressLine2, giftee.AddressLine2En),
+	} {
+		if line != "" {
+			label.Lines = append(label.Lines, line)
+		}
+	}
+
+	var cityLine []string
+	for _, part := range []string{
+		pick(giftee.City, giftee.CityEn),
+		pick(giftee.Region, giftee.RegionEn),
+		pick(giftee.PostalCode, nil),
+	} {
+		if part != "" {
+			cityLine = append(cityLine, part)
+		}
+	}
+	label.Lines = append(label.Lines, strings.Join(cityLine, ", "))
+
+	return label, nil
+}
+
+// ensureParcelCodes выдает коды посылок участникам, у которых их еще нет (розыгрыши до появления кодов)
+func (h *Handler) ensureParcelCodes(members []models.Member) {
+	for i := range members {
+		if members[i].ParcelCode != nil {
+			continue
+		}
+		code, err := generateParcelCode()
+		if err != nil {
+			log.Printf("Failed to generate parcel code: %v", err)
+			continue
+		}
+		members[i].ParcelCode = &code
+		if err := h.DB.Model(&members[i]).Update("parcel_code", code).Error; err != nil {
+			log.Printf("Failed to save parcel code for member %s: %v", members[i].ID, err)
+		}
+	}
+}
+
+// generateParcelCode создает анонимный код посылки вида "SS-K7M2QX"
+func generateParcelCode() (string, error) {
+	code := make([]byte, parcelCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(parcelCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = parcelCodeAlphabet[n.Int64()]
+	}
+	return "SS-" + string(code), nil
+}
+
+func memberCountry(m models.Member) string {
+	if m.Country == nil {
+		return ""
+	}
+	return *m.Country
+}
+
+func renderLabels(c *gin.Context, page labelsPage) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := labelsTemplate.Execute(c.Writer, page); err != nil {
+		log.Printf("Failed to render labels: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"secret-santa/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// Время жизни кэша превью
+const (
+	linkPreviewTTL      = 7 * 24 * time.Hour
+	linkPreviewErrorTTL = time.Hour
+	linkPreviewTimeout  = 10 * time.Second
+)
+
+// LinkPreviewResponse - превью ссылки из вишлиста
+type LinkPreviewResponse struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
+	Price       string `json:"price"`
+	Currency    string `json:"currency"`
+}
+
+func linkHash(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// scheduleLinkPreview загружает превью в фоне, если его нет в кэше или оно устарело
+func (h *Handler) scheduleLinkPreview(rawURL *string) {
+	if rawURL == nil || *rawURL == "" || h.Unfurler == nil {
+		return
+	}
+	url := *rawURL
+
+	var cached models.LinkPreview
+	if err := h.DB.Where("url_hash = ?", linkHash(url)).First(&cached).Error; err == nil && linkPreviewFresh(cached) {
+		return
+	}
+
+	h.startLinkPreview(url)
+}
+
+// linkPreviewFresh - запись кэша еще не устарела (ошибки живут меньше успешных превью)
+func linkPreviewFresh(cached models.LinkPreview) bool {
+	ttl := linkPreviewTTL
+	if cached.Error != nil {
+		ttl = linkPreviewErrorTTL
+	}
+	return time.Since(cached.FetchedAt) < ttl
+}
+
+// startLinkPreview запускает фоновую загрузку превью (одна загрузка на URL одновременно)
+func (h *Handler) startLinkPreview(url string) {
+	if h.Unfurler == nil {
+		return
+	}
+	if _, loading := h.previewsInFlight.LoadOrStore(url, struct{}{}); loading {
+		return
+	}
+
+	go func() {
+		defer h.previewsInFlight.Delete(url)
+		h.refreshLinkPreview(url)
+	}()
+}
+
+// refreshLinkPreview загружает метаданные страницы и сохраняет их в кэш
+func (h *Handler) refreshLinkPreview(url string) {
+	ctx, cancel := context.WithTimeout(context.Background(), linkPreviewTimeout)
+	defer cancel()
+
+	record := models.LinkPreview{
+		URLHash:   linkHash(url),
+		URL:       url,
+		FetchedAt: time.Now(),
+	}
+
+	preview, err := h.Unfurler.Fetch(ctx, url)
+	if err != nil {
+		log.Printf("Failed to fetch link preview for %s: %v", url, err)
+		errText := err.Error()
+		record.Error = &errText
+	} else {
+		record.Title = preview.Title
+		record.Description = preview.Description
+		record.ImageURL = preview.ImageURL
+		record.SiteName = preview.SiteName
+		record.Price = preview.Price
+		if len(preview.Currency) == 3 {
+			record.Currency = preview.Currency
+		}
+	}
+
+	if err := h.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "url_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"title", "description", "image_url", "site_name", "price", "currency", "error", "fetched_at",
+		}),
+	}).Create(&record).Error; err != nil {
+		log.Printf("Failed to save link preview for %s: %v", url, err)
+	}
+}
+
+// linkPreviewsFor возвращает закэшированные превью для ссылок позиций вишлиста (ключ - URL).
+// Отсутствующие и устаревшие превью ставятся в очередь на загрузку.
+func (h *Handler) linkPreviewsFor(items []models.WishlistItem) map[string]*LinkPreviewResponse {
+	previews := make(map[string]*LinkPreviewResponse)
+
+	var hashes []string
+	for _, item := range items {
+		if item.URL != nil && *item.URL != "" {
+			hashes = append(hashes, linkHash(*item.URL))
+		}
+	}
+	if len(hashes) == 0 {
+		return previews
+	}
+
+	var records []models.LinkPreview
+	h.DB.Where("url_hash IN ?", hashes).Find(&records)
+
+	// Устаревшие превью отдаем как есть, пока загружается свежее
+	fresh := make(map[string]bool, len(records))
+	for _, r := range records {
+		fresh[r.URL] = linkPreviewFresh(r)
+		if r.Error != nil {
+			continue
+		}
+		previews[r.URL] = &LinkPreviewResponse{
+			Title:       r.Title,
+			Description: r.Description,
+			ImageURL:    r.ImageURL,
+			SiteName:    r.SiteName,
+			Price:       r.Price,
+			Currency:    r.Currency,
+		}
+	}
+
+	for _, item := range items {
+		if item.URL != nil && *item.URL != "" && !fresh[*item.URL] {
+			h.startLinkPreview(*item.URL)
+		}
+	}
+
+	return previews
+}
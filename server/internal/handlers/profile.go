@@ -32,6 +32,10 @@ type ProfileRequest struct {
 
 	Wishlist     *string `json:"wishlist"`
 	AntiWishlist *string `json:"anti_wishlist"`
+	Timezone     *string `json:"timezone"`
+
+	// Применить изменения к активным розыгрышам (кроме полей, измененных в самом розыгрыше)
+	ApplyToRaffles bool `json:"apply_to_raffles"`
 }
 
 // ProfileResponse - структура ответа с профилем
@@ -58,6 +62,9 @@ type ProfileResponse struct {
 
 	Wishlist     *string `json:"wishlist"`
 	AntiWishlist *string `json:"anti_wishlist"`
+	Timezone     *string `json:"timezone"`
+
+	UpdatedRaffles *int `json:"updated_raffles,omitempty"` // Сколько розыгрышей обновлено (apply_to_raffles)
 }
 
 // GetProfile - получить профиль текущего пользователя
@@ -101,6 +108,7 @@ func (h *Handler) GetProfile(c *gin.Context) {
 		RegionEn:       profile.RegionEn,
 		Wishlist:       profile.Wishlist,
 		AntiWishlist:   profile.AntiWishlist,
+		Timezone:       profile.Timezone,
 	})
 }
 
@@ -179,6 +187,9 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 	if req.AntiWishlist != nil {
 		profileData["anti_wishlist"] = *req.AntiWishlist
 	}
+	if req.Timezone != nil {
+		profileData["timezone"] = *req.Timezone
+	}
 
 	// Проверяем валидность данных
 	if err := validator.ValidateProfileData(profileData); err != nil {
@@ -209,10 +220,21 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 	req.RegionEn = sanitizeStringPtr(req.RegionEn)
 	req.Wishlist = sanitizeStringPtr(req.Wishlist)
 	req.AntiWishlist = sanitizeStringPtr(req.AntiWishlist)
+	req.Timezone = sanitizeStringPtr(req.Timezone)
 
 	var profile models.UserProfile
 	result := h.DB.Where("user_id = ?", userID).First(&profile)
 
+	// Проверка адреса по правилам страны и автозаполнение английских полей
+	if err := normalizeAddressFields(addressFields{
+		Line1: &req.AddressLine1, Line2: &req.AddressLine2, City: &req.City, Region: &req.Region,
+		PostalCode: &req.PostalCode, Country: &req.Country,
+		Line1En: &req.AddressLine1En, Line2En: &req.AddressLine2En, CityEn: &req.CityEn, RegionEn: &req.RegionEn,
+	}, localAddress(profile.AddressLine1, profile.AddressLine2, profile.City, profile.Region)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+		return
+	}
+
 	if result.Error != nil {
 		// Профиль не существует - создаем новый
 		profile = models.UserProfile{
@@ -231,6 +253,7 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 			RegionEn:       req.RegionEn,
 			Wishlist:       req.Wishlist,
 			AntiWishlist:   req.AntiWishlist,
+			Timezone:       req.Timezone,
 		}
 		if err := h.DB.Create(&profile).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile"})
@@ -252,6 +275,7 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		profile.RegionEn = req.RegionEn
 		profile.Wishlist = req.Wishlist
 		profile.AntiWishlist = req.AntiWishlist
+		profile.Timezone = req.Timezone
 
 		if err := h.DB.Save(&profile).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
@@ -259,6 +283,12 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		}
 	}
 
+	var updatedRaffles *int
+	if req.ApplyToRaffles {
+		updated := h.applyProfileToRaffles(profile.UserID, profile)
+		updatedRaffles = &updated
+	}
+
 	c.JSON(http.StatusOK, ProfileResponse{
 		ID:             profile.ID,
 		UserID:         profile.UserID,
@@ -277,5 +307,7 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		RegionEn:       profile.RegionEn,
 		Wishlist:       profile.Wishlist,
 		AntiWishlist:   profile.AntiWishlist,
+		Timezone:       profile.Timezone,
+		UpdatedRaffles: updatedRaffles,
 	})
 }
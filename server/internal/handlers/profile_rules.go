@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"secret-santa/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// requiredFieldOptions - допустимые значения RequiredFields
+var requiredFieldOptions = []string{
+	models.RequiredFieldAddress,
+	models.RequiredFieldAddressEn,
+	models.RequiredFieldPhone,
+	models.RequiredFieldWishlist,
+}
+
+// RequiredFieldsRequest - обязательные поля профиля в розыгрыше
+type RequiredFieldsRequest struct {
+	RequiredFields []string `json:"requiredFields"`
+}
+
+// IncompleteProfileResponse - участник с незаполненным профилем
+type IncompleteProfileResponse struct {
+	MemberID         string   `json:"memberId"`
+	Name             string   `json:"name"`
+	MissingFields    []string `json:"missingFields"`
+	MissingQuestions []string `json:"missingQuestions"` // Тексты обязательных вопросов без ответа
+}
+
+// UpdateRequiredFields - организатор задает, какие поля профиля обязательны для жеребьевки
+func (h *Handler) UpdateRequiredFields(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var req RequiredFieldsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var group models.Group
+	if err := h.DB.First(&group, rid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	if group.OwnerID != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only raffle owner can change required fields"})
+		return
+	}
+
+	if group.IsDrawn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change required fields after draw"})
+		return
+	}
+
+	fields, err := normalizeRequiredFields(req.RequiredFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Updates со структурой, чтобы сработал JSON-сериализатор поля
+	if err := h.DB.Model(&group).Select("required_fields").Updates(&models.Group{RequiredFields: fields}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update required fields"})
+		return
+	}
+
+	h.DB.Preload("Members").Preload("Members.User").Preload("Members.WishlistItems").First(&group, rid)
+	c.JSON(http.StatusOK, h.raffleToResponse(group, uid, h.loadExchangeRates()))
+}
+
+// normalizeRequiredFields проверяет список обязательных полей и убирает повторы
+func normalizeRequiredFields(fields []string) ([]string, error) {
+	result := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !containsString(requiredFieldOptions, field) {
+			return nil, fmt.Errorf("unknown required field: %s (allowed: %s)", field, strings.Join(requiredFieldOptions, ", "))
+		}
+		if !containsString(result, field) {
+			result = append(result, field)
+		}
+	}
+	return result, nil
+}
+
+// missingProfileFields возвращает незаполненные обязательные поля и обязательные вопросы участника
+func missingProfileFields(m models.Member, g models.Group) (fields []string, questions []string) {
+	hasAddress := nonEmpty(m.AddressLine1) && nonEmpty(m.City) && nonEmpty(m.Country)
+	hasWishlist := nonEmpty(m.Wishlist) || len(m.WishlistItems) > 0
+
+	if len(g.RequiredFields) == 0 {
+		// Правило по умолчанию: есть хотя бы адрес или вишлист
+		if !hasAddress && !hasWishlist {
+			fields = append(fields, models.RequiredFieldAddress, models.RequiredFieldWishlist)
+		}
+	}
+
+	// Домашний адрес не нужен тем, кто получает подарок в офисе или лично
+	needsHomeAddress := m.AddressPrivacy != models.AddressPrivacyOffice &&
+		m.AddressPrivacy != models.AddressPrivacyHandDelivery
+
+	for _, field := range g.RequiredFields {
+		switch field {
+		case models.RequiredFieldAddress:
+			if needsHomeAddress && !hasAddress {
+				fields = append(fields, field)
+			}
+		case models.RequiredFieldAddressEn:
+			if needsHomeAddress && (!nonEmpty(m.AddressLine1En) || !nonEmpty(m.CityEn)) {
+				fields = append(fields, field)
+			}
+		case models.RequiredFieldPhone:
+			if !nonEmpty(m.Phone) {
+				fields = append(fields, field)
+			}
+		case models.RequiredFieldWishlist:
+			if !hasWishlist {
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	// Вопросы анкеты обязательны по своему флагу Required
+	for _, q := range g.Questions {
+		if q.Required && m.Answers[q.ID] == "" {
+			questions = append(questions, q.Label)
+		}
+	}
+
+	return fields, questions
+}
+
+// incompleteProfiles - участники, которым нужно дозаполнить профиль перед жеребьевкой
+func incompleteProfiles(g models.Group) []IncompleteProfileResponse {
+	var result []IncompleteProfileResponse
+	for _, m := range g.Members {
+		fields, questions := missingProfileFields(m, g)
+		if len(fields) == 0 && len(questions) == 0 {
+			continue
+		}
+		result = append(result, IncompleteProfileResponse{
+			MemberID:         m.ID.String(),
+			Name:             m.User.Name,
+			MissingFields:    fields,
+			MissingQuestions: questions,
+		})
+	}
+	return result
+}
+
+func nonEmpty(s *string) bool {
+	return s != nil && strings.TrimSpace(*s) != ""
+}
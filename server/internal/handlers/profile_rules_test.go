@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"secret-santa/internal/models"
+)
+
+func TestMissingProfileFields(t *testing.T) {
+	home := func(m models.Member) models.Member {
+		m.AddressLine1 = strPtr("ул. Тверская, 1")
+		m.City = strPtr("Москва")
+		m.Country = strPtr("RU")
+		return m
+	}
+	homeEn := func(m models.Member) models.Member {
+		m.AddressLine1En = strPtr("ul. Tverskaia, 1")
+		m.CityEn = strPtr("Moskva")
+		return m
+	}
+	all := []string{models.RequiredFieldAddress, models.RequiredFieldAddressEn}
+
+	tests := []struct {
+		name          string
+		member        models.Member
+		required      []string
+		questions     []models.ProfileQuestion
+		wantFields    []string
+		wantQuestions []string
+	}{
+		// Правило по умолчанию: адрес или вишлист
+		{
+			name:       "default rule, empty profile",
+			member:     models.Member{},
+			wantFields: []string{models.RequiredFieldAddress, models.RequiredFieldWishlist},
+		},
+		{
+			name:   "default rule, address only",
+			member: home(models.Member{}),
+		},
+		{
+			name:   "default rule, wishlist text only",
+			member: models.Member{Wishlist: strPtr("Книги")},
+		},
+		{
+			name:   "default rule, wishlist items only",
+			member: models.Member{WishlistItems: []models.WishlistItem{{}}},
+		},
+		{
+			name:       "default rule, blank wishlist and partial address",
+			member:     models.Member{Wishlist: strPtr("  "), AddressLine1: strPtr("ул. Тверская, 1")},
+			wantFields: []string{models.RequiredFieldAddress, models.RequiredFieldWishlist},
+		},
+		{
+			name:       "default rule is off when fields are configured",
+			member:     models.Member{},
+			required:   []string{models.RequiredFieldPhone},
+			wantFields: []string{models.RequiredFieldPhone},
+		},
+
+		// Адрес и адрес на английском
+		{
+			name:       "address required and missing",
+			member:     models.Member{Wishlist: strPtr("Книги")},
+			required:   all,
+			wantFields: all,
+		},
+		{
+			name:       "English address required, only local filled",
+			member:     home(models.Member{}),
+			required:   all,
+			wantFields: []string{models.RequiredFieldAddressEn},
+		},
+		{
+			name:     "full address filled",
+			member:   homeEn(home(models.Member{})),
+			required: all,
+		},
+		{
+			name:     "office delivery needs no home address",
+			member:   models.Member{AddressPrivacy: models.AddressPrivacyOffice},
+			required: all,
+		},
+		{
+			name:     "hand delivery needs no home address",
+			member:   models.Member{AddressPrivacy: models.AddressPrivacyHandDelivery},
+			required: all,
+		},
+		{
+			name:       "regular delivery needs the address",
+			member:     models.Member{AddressPrivacy: models.AddressPrivacyFull},
+			required:   all,
+			wantFields: all,
+		},
+		{
+			name:       "office delivery still needs phone and wishlist",
+			member:     models.Member{AddressPrivacy: models.AddressPrivacyOffice},
+			required:   []string{models.RequiredFieldAddress, models.RequiredFieldPhone, models.RequiredFieldWishlist},
+			wantFields: []string{models.RequiredFieldPhone, models.RequiredFieldWishlist},
+		},
+
+		// Обязательные вопросы анкеты
+		{
+			name:   "required question without answer",
+			member: models.Member{Wishlist: strPtr("Книги"), Answers: map[string]string{"size": "M"}},
+			questions: []models.ProfileQuestion{
+				{ID: "size", Label: "Размер одежды", Required: true},
+				{ID: "color", Label: "Любимый цвет", Required: true},
+				{ID: "pets", Label: "Есть ли животные"},
+			},
+			wantQuestions: []string{"Любимый цвет"},
+		},
+		{
+			name:   "required question without any answers",
+			member: models.Member{Wishlist: strPtr("Книги")},
+			questions: []models.ProfileQuestion{
+				{ID: "size", Label: "Размер одежды", Required: true},
+			},
+			wantQuestions: []string{"Размер одежды"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := models.Group{RequiredFields: tt.required, Questions: tt.questions}
+			fields, questions := missingProfileFields(tt.member, group)
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+			}
+			if !reflect.DeepEqual(questions, tt.wantQuestions) {
+				t.Errorf("questions = %v, want %v", questions, tt.wantQuestions)
+			}
+		})
+	}
+}
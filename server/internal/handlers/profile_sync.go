@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"secret-santa/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// profileField - поле профиля, которое копируется из UserProfile в участника розыгрыша
+type profileField struct {
+	Name    string // Имя поля в JSON
+	Address bool   // Часть адреса доставки (об изменении сообщаем дарителю)
+	profile func(*models.UserProfile) **string
+	member  func(*models.Member) **string
+	saved   func(*models.SavedAddress) **string // nil - поле не хранится в адресной книге
+}
+
+// profileFields - все копируемые поля профиля
+var profileFields = []profileField{
+	{"phone", true, func(p *models.UserProfile) **string { return &p.Phone }, func(m *models.Member) **string { return &m.Phone }, func(a *models.SavedAddress) **string { return &a.Phone }},
+	{"about", false, func(p *models.UserProfile) **string { return &p.About }, func(m *models.Member) **string { return &m.About }, nil},
+	{"address_line1", true, func(p *models.UserProfile) **string { return &p.AddressLine1 }, func(m *models.Member) **string { return &m.AddressLine1 }, func(a *models.SavedAddress) **string { return &a.AddressLine1 }},
+	{"address_line2", true, func(p *models.UserProfile) **string { return &p.AddressLine2 }, func(m *models.Member) **string { return &m.AddressLine2 }, func(a *models.SavedAddress) **string { return &a.AddressLine2 }},
+	{"city", true, func(p *models.UserProfile) **string { return &p.City }, func(m *models.Member) **string { return &m.City }, func(a *models.SavedAddress) **string { return &a.City }},
+	{"region", true, func(p *models.UserProfile) **string { return &p.Region }, func(m *models.Member) **string { return &m.Region }, func(a *models.SavedAddress) **string { return &a.Region }},
+	{"postal_code", true, func(p *models.UserProfile) **string { return &p.PostalCode }, func(m *models.Member) **string { return &m.PostalCode }, func(a *models.SavedAddress) **string { return &a.PostalCode }},
+	{"country", true, func(p *models.UserProfile) **string { return &p.Country }, func(m *models.Member) **string { return &m.Country }, func(a *models.SavedAddress) **string { return &a.Country }},
+	{"address_line1_en", true, func(p *models.UserProfile) **string { return &p.AddressLine1En }, func(m *models.Member) **string { return &m.AddressLine1En }, func(a *models.SavedAddress) **string { return &a.AddressLine1En }},
+	{"address_line2_en", true, func(p *models.UserProfile) **string { return &p.AddressLine2En }, func(m *models.Member) **string { return &m.AddressLine2En }, func(a *models.SavedAddress) **string { return &a.AddressLine2En }},
+	{"city_en", true, func(p *models.UserProfile) **string { return &p.CityEn }, func(m *models.Member) **string { return &m.CityEn }, func(a *models.SavedAddress) **string { return &a.CityEn }},
+	{"region_en", true, func(p *models.UserProfile) **string { return &p.RegionEn }, func(m *models.Member) **string { return &m.RegionEn }, func(a *models.SavedAddress) **string { return &a.RegionEn }},
+	{"wishlist", false, func(p *models.UserProfile) **string { return &p.Wishlist }, func(m *models.Member) **string { return &m.Wishlist }, nil},
+	{"anti_wishlist", false, func(p *models.UserProfile) **string { return &p.AntiWishlist }, func(m *models.Member) **string { return &m.AntiWishlist }, nil},
+	{"timezone", false, func(p *models.UserProfile) **string { return &p.Timezone }, func(m *models.Member) **string { return &m.Timezone }, nil},
+}
+
+// GifteeAddressChangedEvent - событие для дарителя: получатель сменил адрес
+type GifteeAddressChangedEvent struct {
+	Type      string    `json:"type"` // "giftee_address_changed"
+	Fields    []string  `json:"fields"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// markOverriddenFields отмечает поля, которые участник изменил в конкретном розыгрыше:
+// при обновлении основного профиля они не перезаписываются. Поля из skip (например, заполненные
+// из адресной книги) изменены не участником и не отмечаются.
+func markOverriddenFields(before, after *models.Member, skip ...string) {
+	for _, f := range profileFields {
+		if equalStringPtr(*f.member(before), *f.member(after)) || containsString(after.OverriddenFields, f.Name) ||
+			containsString(skip, f.Name) {
+			continue
+		}
+		after.OverriddenFields = append(after.OverriddenFields, f.Name)
+	}
+}
+
+// clearAddressOverrides снимает отметки с полей адреса: они снова обновляются из основного профиля
+func clearAddressOverrides(m *models.Member) {
+	fields := m.OverriddenFields[:0:0]
+	for _, name := range m.OverriddenFields {
+		if !isAddressField(name) {
+			fields = append(fields, name)
+		}
+	}
+	m.OverriddenFields = fields
+}
+
+func isAddressField(name string) bool {
+	for _, f := range profileFields {
+		if f.Name == name {
+			return f.Address
+		}
+	}
+	return false
+}
+
+// applyProfileToRaffles переносит основной профиль во все активные розыгрыши пользователя.
+// Поля, переопределенные в розыгрыше, не трогаем. Возвращает число обновленных розыгрышей.
+func (h *Handler) applyProfileToRaffles(userID uuid.UUID, profile models.UserProfile) int {
+	var members []models.Member
+	if err := h.DB.Preload("Group").Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		log.Printf("Failed to load raffles for profile sync: %v", err)
+		return 0
+	}
+
+	now := time.Now()
+	updated := 0
+	for i := range members {
+		m := &members[i]
+		if !isRaffleActive(m.Group, now) {
+			continue
+		}
+
+		var columns, changedAddress []string
+		for _, f := range profileFields {
+			if containsString(m.OverriddenFields, f.Name) {
+				continue
+			}
+			// Адрес, выбранный из адресной книги, обновляется вместе с самой записью книги
+			if f.Address && m.AddressID != nil {
+				continue
+			}
+			value := *f.profile(&profile)
+			if equalStringPtr(*f.member(m), value) {
+				continue
+			}
+			*f.member(m) = value
+			columns = append(columns, f.Name) // Имя поля совпадает с колонкой
+			if f.Address {
+				changedAddress = append(changedAddress, f.Name)
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		// Только синхронизируемые колонки: получатель, ответы и прочее могли измениться параллельно
+		if err := h.DB.Model(m).Select(columns).Updates(m).Error; err != nil {
+			log.Printf("Failed to sync profile to member %s: %v", m.ID, err)
+			continue
+		}
+		updated++
+
+		h.notifyAddressChanged(m, changedAddress)
+	}
+
+	return updated
+}
+
+// notifyAddressChanged сообщает дарителям, которые уже вытянули участника, о новом адресе.
+// Отметка сохраняется в участнике, чтобы даритель увидел ее и без открытого чата (GetMyGiftee).
+func (h *Handler) notifyAddressChanged(m *models.Member, fields []string) {
+	if !m.Group.IsDrawn || len(fields) == 0 {
+		return
+	}
+
+	now := time.Now()
+	m.AddressChangedAt = &now
+	m.AddressChangedFields = fields
+	// Updates со структурой, чтобы сработал JSON-сериализатор поля
+	if err := h.DB.Model(m).Select("address_changed_at", "address_changed_fields").
+		Updates(&models.Member{AddressChangedAt: &now, AddressChangedFields: fields}).Error; err != nil {
+		log.Printf("Failed to save address change of member %s: %v", m.ID, err)
+	}
+
+	var santas []models.Member
+	h.DB.Where("group_id = ? AND giftee_id = ?", m.GroupID, m.ID).Find(&santas)
+	for _, santa := range santas {
+		h.Hub.NotifyMember(santa.GroupID, santa.ID, GifteeAddressChangedEvent{
+			Type:      "giftee_address_changed",
+			Fields:    fields,
+			ChangedAt: now,
+		})
+	}
+}
+
+// addressFieldsChanged - в участнике изменилось хотя бы одно адресное поле
+func addressFieldsChanged(before, after *models.Member) bool {
+	for _, f := range profileFields {
+		if f.Address && !equalStringPtr(*f.member(before), *f.member(after)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRaffleActive - розыгрыш еще не завершен (не раскрыт и дата события не прошла)
+func isRaffleActive(g models.Group, now time.Time) bool {
+	return g.RevealedAt == nil && (g.EventDate == nil || g.EventDate.After(now))
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
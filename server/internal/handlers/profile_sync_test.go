@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"secret-santa/internal/models"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestMarkOverriddenFieldsSkipsSavedAddress(t *testing.T) {
+	before := models.Member{City: strPtr("Moscow"), Wishlist: strPtr("Books")}
+	after := before
+	saved := models.SavedAddress{City: strPtr("Kazan"), Country: strPtr("RU")}
+
+	after.Wishlist = strPtr("Socks")
+	applySavedAddress(&after, &saved)
+
+	var skip []string
+	for _, f := range savedAddressFields(&saved) {
+		skip = append(skip, f.Name)
+	}
+	markOverriddenFields(&before, &after, skip...)
+
+	if want := []string{"wishlist"}; !reflect.DeepEqual(after.OverriddenFields, want) {
+		t.Errorf("OverriddenFields = %v, want %v", after.OverriddenFields, want)
+	}
+}
+
+func TestSavedAddressFieldsPhone(t *testing.T) {
+	withPhone := models.SavedAddress{Phone: strPtr("+79990000000")}
+	if !containsField(savedAddressFields(&withPhone), "phone") {
+		t.Error("phone from the address book is not applied")
+	}
+	// Адрес без телефона не стирает телефон участника
+	if containsField(savedAddressFields(&models.SavedAddress{}), "phone") {
+		t.Error("empty phone from the address book is applied")
+	}
+}
+
+func TestClearAddressOverrides(t *testing.T) {
+	m := models.Member{OverriddenFields: []string{"city", "wishlist", "phone", "postal_code", "timezone"}}
+	original := m.OverriddenFields
+
+	clearAddressOverrides(&m)
+
+	if want := []string{"wishlist", "timezone"}; !reflect.DeepEqual(m.OverriddenFields, want) {
+		t.Errorf("OverriddenFields = %v, want %v", m.OverriddenFields, want)
+	}
+	if original[0] != "city" {
+		t.Error("clearAddressOverrides modified the original slice")
+	}
+}
+
+func containsField(fields []profileField, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"secret-santa/internal/models"
+	"secret-santa/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Ограничения анкеты розыгрыша
+const (
+	MaxProfileQuestions  = 20
+	MaxQuestionOptions   = 20
+	MaxQuestionLength    = 200
+	MaxAnswerLength      = 500
+	maxQuestionIDLength  = 32
+	questionIDRandomSize = 4
+)
+
+// ProfileQuestionsRequest - анкета розыгрыша целиком
+type ProfileQuestionsRequest struct {
+	Questions []models.ProfileQuestion `json:"questions"`
+}
+
+// QuestionAnswerResponse - ответ получателя на вопрос анкеты (для дарителя)
+type QuestionAnswerResponse struct {
+	QuestionID string `json:"question_id"`
+	Label      string `json:"label"`
+	Type       string `json:"type"`
+	Answer     string `json:"answer"`
+}
+
+// UpdateProfileQuestions - организатор задает вопросы анкеты розыгрыша
+func (h *Handler) UpdateProfileQuestions(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var req ProfileQuestionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var group models.Group
+	if err := h.DB.First(&group, rid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	if group.OwnerID != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only raffle owner can edit questions"})
+		return
+	}
+
+	// После жеребьевки новые обязательные вопросы уже ни на что не влияют
+	if group.IsDrawn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change questions after draw"})
+		return
+	}
+
+	questions, err := normalizeProfileQuestions(req.Questions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid questions: " + err.Error()})
+		return
+	}
+
+	// Updates со структурой, чтобы сработал JSON-сериализатор поля
+	group.Questions = questions
+	if err := h.DB.Model(&group).Select("questions").Updates(&models.Group{Questions: questions}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update questions"})
+		return
+	}
+
+	h.DB.Preload("Members").Preload("Members.User").Preload("Members.WishlistItems").First(&group, rid)
+	c.JSON(http.StatusOK, h.raffleToResponse(group, uid, h.loadExchangeRates()))
+}
+
+// normalizeProfileQuestions проверяет анкету и выдает ID новым вопросам
+// (ID существующих сохраняются, чтобы не потерять уже данные ответы)
+func normalizeProfileQuestions(questions []models.ProfileQuestion) ([]models.ProfileQuestion, error) {
+	if len(questions) > MaxProfileQuestions {
+		return nil, fmt.Errorf("at most %d questions allowed", MaxProfileQuestions)
+	}
+
+	result := make([]models.ProfileQuestion, 0, len(questions))
+	seen := make(map[string]bool, len(questions))
+	for _, q := range questions {
+		q.Label = validator.SanitizeString(q.Label)
+		if q.Label == "" {
+			return nil, errors.New("question label is required")
+		}
+		if err := validator.ValidateProfileField("label", q.Label, MaxQuestionLength); err != nil {
+			return nil, err
+		}
+
+		q.Type = strings.ToLower(strings.TrimSpace(q.Type))
+		switch q.Type {
+		case models.QuestionTypeText, models.QuestionTypeNumber:
+			q.Options = nil
+		case models.QuestionTypeChoice:
+			options, err := normalizeQuestionOptions(q.Options)
+			if err != nil {
+				return nil, err
+			}
+			q.Options = options
+		default:
+			return nil, fmt.Errorf("invalid question type: %s", q.Type)
+		}
+
+		q.ID = strings.TrimSpace(q.ID)
+		if q.ID == "" {
+			id, err := generateQuestionID()
+			if err != nil {
+				return nil, err
+			}
+			q.ID = id
+		}
+		if len(q.ID) > maxQuestionIDLength || seen[q.ID] {
+			return nil, errors.New("invalid question id")
+		}
+		seen[q.ID] = true
+
+		result = append(result, q)
+	}
+	return result, nil
+}
+
+func normalizeQuestionOptions(options []string) ([]string, error) {
+	if len(options) < 2 || len(options) > MaxQuestionOptions {
+		return nil, fmt.Errorf("choice question needs 2 to %d options", MaxQuestionOptions)
+	}
+
+	result := make([]string, 0, len(options))
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		option = validator.SanitizeString(option)
+		if option == "" || seen[option] {
+			return nil, errors.New("options must be non-empty and unique")
+		}
+		if err := validator.ValidateProfileField("option", option, MaxQuestionLength); err != nil {
+			return nil, err
+		}
+		seen[option] = true
+		result = append(result, option)
+	}
+	return result, nil
+}
+
+// validateAnswers проверяет ответы участника по анкете розыгрыша.
+// Пустой ответ удаляет ответ; обязательность проверяется в isProfileFilled, а не при сохранении.
+func validateAnswers(answers map[string]string, questions []models.ProfileQuestion) (map[string]string, error) {
+	byID := make(map[string]models.ProfileQuestion, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+
+	result := make(map[string]string, len(answers))
+	for id, answer := range answers {
+		q, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown question: %s", id)
+		}
+
+		answer = validator.SanitizeString(answer)
+		if answer == "" {
+			continue
+		}
+		if err := validator.ValidateProfileField(q.Label, answer, MaxAnswerLength); err != nil {
+			return nil, err
+		}
+
+		switch q.Type {
+		case models.QuestionTypeNumber:
+			if _, err := strconv.ParseFloat(strings.ReplaceAll(answer, ",", "."), 64); err != nil {
+				return nil, fmt.Errorf("%s: expected a number", q.Label)
+			}
+		case models.QuestionTypeChoice:
+			if !containsString(q.Options, answer) {
+				return nil, fmt.Errorf("%s: choose one of the options", q.Label)
+			}
+		}
+
+		result[id] = answer
+	}
+	return result, nil
+}
+
+// answersToResponse - ответы в порядке вопросов анкеты
+func answersToResponse(answers map[string]string, questions []models.ProfileQuestion) []QuestionAnswerResponse {
+	response := make([]QuestionAnswerResponse, 0, len(questions))
+	for _, q := range questions {
+		answer, ok := answers[q.ID]
+		if !ok {
+			continue
+		}
+		response = append(response, QuestionAnswerResponse{
+			QuestionID: q.ID,
+			Label:      q.Label,
+			Type:       q.Type,
+			Answer:     answer,
+		})
+	}
+	return response
+}
+
+func generateQuestionID() (string, error) {
+	b := make([]byte, questionIDRandomSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "q" + hex.EncodeToString(b), nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
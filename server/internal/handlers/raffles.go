@@ -3,23 +3,51 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"log"
 	"math/big"
 	mathrand "math/rand"
 	"net/http"
+	"strings"
 	"time"
 
+	"secret-santa/internal/currency"
 	"secret-santa/internal/models"
+	"secret-santa/internal/validator"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type CreateRaffleRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
 	AvatarURL   string `json:"avatarUrl"` // URL уже загруженного аватара
-	Budget      string `json:"budget"`
-	EventDate   string `json:"eventDate"`
+	Budget      string `json:"budget"`    // Старый формат: "1000-3000 RUB"
+	EventDate   string `json:"eventDate"` // RFC3339 или локальное время розыгрыша ("2006-01-02T15:04", "2006-01-02")
+
+	// Часовой пояс розыгрыша (IANA) и дедлайны в том же формате, что eventDate
+	Timezone     string `json:"timezone"`
+	JoinDeadline string `json:"joinDeadline"`
+	DrawDeadline string `json:"drawDeadline"`
+
+	// Структурированный бюджет (приоритетнее строки budget)
+	BudgetMin      *int64 `json:"budgetMin"`
+	BudgetMax      *int64 `json:"budgetMax"`
+	BudgetCurrency string `json:"budgetCurrency"`
+
+	// Адрес офиса для самовывоза (для участников, не желающих делиться домашним адресом)
+	OfficeAddress string `json:"officeAddress"`
+
+	// Дополнительные вопросы анкеты участника
+	Questions []models.ProfileQuestion `json:"questions"`
+
+	// Обязательные поля профиля (address, address_en, phone, wishlist)
+	RequiredFields []string `json:"requiredFields"`
+
+	// Продолжение серии: ID прошлого розыгрыша того же организатора (общий рейтинг "Угадай Санту")
+	PreviousRaffleID string `json:"previousRaffleId"`
 }
 
 type RaffleResponse struct {
@@ -35,6 +63,45 @@ type RaffleResponse struct {
 	OwnerID     string           `json:"ownerId"`
 	Members     []MemberResponse `json:"members"`
 	CreatedAt   string           `json:"createdAt"`
+
+	BudgetMin      *int64          `json:"budgetMin"`
+	BudgetMax      *int64          `json:"budgetMax"`
+	BudgetCurrency *string         `json:"budgetCurrency"`
+	BudgetLocal    *BudgetResponse `json:"budgetLocal"` // Бюджет в валюте текущего участника
+
+	Timezone     string             `json:"timezone"`
+	JoinDeadline *string            `json:"joinDeadline"`
+	DrawDeadline *string            `json:"drawDeadline"`
+	Countdown    *CountdownResponse `json:"countdown"` // Отсчет в часовом поясе текущего участника
+
+	RevealedAt    *string `json:"revealedAt"` // Дарители раскрыты
+	SeriesID      *string `json:"seriesId"`
+	OfficeAddress *string `json:"officeAddress"`
+
+	Questions      []models.ProfileQuestion `json:"questions"`
+	RequiredFields []string                 `json:"requiredFields"`
+}
+
+// CountdownResponse - время до событий розыгрыша в часовом поясе участника
+type CountdownResponse struct {
+	Timezone     string            `json:"timezone"`
+	Event        *DeadlineResponse `json:"event"`
+	JoinDeadline *DeadlineResponse `json:"joinDeadline"`
+	DrawDeadline *DeadlineResponse `json:"drawDeadline"`
+}
+
+// DeadlineResponse - отсчет до конкретного момента
+type DeadlineResponse struct {
+	At          string `json:"at"`          // Локальное время участника (RFC3339)
+	DaysLeft    int    `json:"daysLeft"`    // Календарных дней по часовому поясу участника
+	SecondsLeft int64  `json:"secondsLeft"` // Отрицательное, если момент прошел
+}
+
+// BudgetResponse - бюджет, пересчитанный в другую валюту
+type BudgetResponse struct {
+	Min      *int64 `json:"min"`
+	Max      *int64 `json:"max"`
+	Currency string `json:"currency"`
 }
 
 type MemberResponse struct {
@@ -43,6 +110,9 @@ type MemberResponse struct {
 	Name            string  `json:"name"`
 	AvatarURL       *string `json:"avatarUrl"`
 	IsProfileFilled bool    `json:"isProfileFilled"`
+
+	MissingFields    []string `json:"missingFields"`
+	MissingQuestions []string `json:"missingQuestions"`
 }
 
 // Профиль участника в розыгрыше (для обновления своего профиля)
@@ -61,6 +131,17 @@ type ParticipantProfileRequest struct {
 	RegionEn       *string `json:"region_en"`
 	Wishlist       *string `json:"wishlist"`
 	AntiWishlist   *string `json:"anti_wishlist"`
+	Timezone       *string `json:"timezone"`
+	StayAnonymous  *bool   `json:"stay_anonymous"`
+
+	AddressPrivacy    *string `json:"address_privacy"` // full, office, delayed, hand_delivery
+	AddressRevealDays *int    `json:"address_reveal_days"`
+
+	Answers map[string]string `json:"answers"` // Ответы на вопросы розыгрыша (не передано - не меняются)
+
+	// Адрес из адресной книги ("" - отвязать) и пресет профиля; перекрывают поля запроса
+	AddressID *string `json:"address_id"`
+	PresetID  *string `json:"preset_id"`
 }
 
 // Ответ с профилем участника
@@ -80,6 +161,22 @@ type ParticipantProfileResponse struct {
 	RegionEn       *string `json:"region_en"`
 	Wishlist       *string `json:"wishlist"`
 	AntiWishlist   *string `json:"anti_wishlist"`
+	Timezone       *string `json:"timezone"`
+	StayAnonymous  bool    `json:"stay_anonymous"`
+
+	AddressPrivacy    string `json:"address_privacy"`
+	AddressRevealDays *int   `json:"address_reveal_days"`
+
+	Questions []models.ProfileQuestion `json:"questions"`
+	Answers   map[string]string        `json:"answers"`
+
+	AddressID *uuid.UUID `json:"address_id"` // Адрес из адресной книги, если выбран
+}
+
+// JoinRaffleRequest - необязательный выбор адреса и пресета профиля при вступлении
+type JoinRaffleRequest struct {
+	AddressID *string `json:"address_id"`
+	PresetID  *string `json:"preset_id"`
 }
 
 // Полная информация о получателе подарка
@@ -101,6 +198,22 @@ type GifteeResponse struct {
 	RegionEn       *string `json:"region_en"`
 	Wishlist       *string `json:"wishlist"`
 	AntiWishlist   *string `json:"anti_wishlist"`
+
+	WishlistItems       []GifteeWishlistItemResponse `json:"wishlist_items"`
+	HiddenWishlistItems int                          `json:"hidden_wishlist_items"` // Скрыто фильтром бюджета
+
+	// Приватность адреса: при AddressHidden адрес и телефон не передаются
+	AddressPrivacy     string  `json:"address_privacy"`
+	AddressHidden      bool    `json:"address_hidden"`
+	AddressAvailableAt *string `json:"address_available_at"` // Когда адрес откроется (для delayed)
+	OfficeAddress      *string `json:"office_address"`       // Куда отправлять подарок (для office)
+	ParcelCode         *string `json:"parcel_code"`          // Написать на посылке при централизованной отправке
+
+	Answers []QuestionAnswerResponse `json:"answers"` // Ответы на вопросы анкеты розыгрыша
+
+	// Последняя смена адреса после жеребьевки (nil - адрес не менялся)
+	AddressChangedAt     *time.Time `json:"address_changed_at"`
+	AddressChangedFields []string   `json:"address_changed_fields"`
 }
 
 type AssignmentResponse struct {
@@ -122,12 +235,14 @@ func (h *Handler) GetRaffles(c *gin.Context) {
 
 	var groups []models.Group
 	if len(groupIDs) > 0 {
-		h.DB.Preload("Members").Preload("Members.User").Where("id IN ?", groupIDs).Find(&groups)
+		h.DB.Preload("Members").Preload("Members.User").Preload("Members.WishlistItems").Where("id IN ?", groupIDs).Find(&groups)
 	}
 
+	// Курсы валют одни на весь список
+	rates := h.loadExchangeRates()
 	response := make([]RaffleResponse, len(groups))
 	for i, g := range groups {
-		response[i] = h.raffleToResponse(g, uid)
+		response[i] = h.raffleToResponse(g, uid, rates)
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -146,12 +261,90 @@ func (h *Handler) CreateRaffle(c *gin.Context) {
 	// Generate invite code
 	inviteCode, _ := generateInviteCode()
 
-	// Parse event date if provided
-	var eventDate *time.Time
-	if req.EventDate != "" {
-		parsed, err := time.Parse("2006-01-02", req.EventDate)
-		if err == nil {
-			eventDate = &parsed
+	// Часовой пояс розыгрыша: все даты без смещения трактуются в нем
+	timezone := strings.TrimSpace(req.Timezone)
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if err := validator.ValidateTimezone(timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	loc, _ := time.LoadLocation(timezone)
+
+	eventDate, err := parseRaffleTime(req.EventDate, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid eventDate: " + err.Error()})
+		return
+	}
+	joinDeadline, err := parseRaffleTime(req.JoinDeadline, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid joinDeadline: " + err.Error()})
+		return
+	}
+	drawDeadline, err := parseRaffleTime(req.DrawDeadline, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid drawDeadline: " + err.Error()})
+		return
+	}
+
+	// Порядок: вступление -> жеребьевка -> событие
+	if joinDeadline != nil && drawDeadline != nil && joinDeadline.After(*drawDeadline) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "joinDeadline must be before drawDeadline"})
+		return
+	}
+	if eventDate != nil {
+		if joinDeadline != nil && joinDeadline.After(*eventDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "joinDeadline must be before eventDate"})
+			return
+		}
+		if drawDeadline != nil && drawDeadline.After(*eventDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "drawDeadline must be before eventDate"})
+			return
+		}
+	}
+
+	// Бюджет: структурированные поля или строка старого формата
+	budget, err := parseBudgetRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget: " + err.Error()})
+		return
+	}
+
+	officeAddress, err := normalizeOfficeAddress(req.OfficeAddress)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid officeAddress: " + err.Error()})
+		return
+	}
+
+	questions, err := normalizeProfileQuestions(req.Questions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid questions: " + err.Error()})
+		return
+	}
+
+	requiredFields, err := normalizeRequiredFields(req.RequiredFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Серия: новый розыгрыш наследует серию прошлого (или прошлый становится ее началом)
+	var previous *models.Group
+	if req.PreviousRaffleID != "" {
+		previousID, err := uuid.Parse(req.PreviousRaffleID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid previousRaffleId"})
+			return
+		}
+		previous = &models.Group{}
+		if err := h.DB.First(previous, previousID).Error; err != nil || previous.OwnerID != uid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Previous raffle not found"})
+			return
+		}
+		if previous.SeriesID == nil {
+			previous.SeriesID = &previous.ID
+			h.DB.Model(previous).Update("series_id", previous.ID)
 		}
 	}
 
@@ -165,9 +358,28 @@ func (h *Handler) CreateRaffle(c *gin.Context) {
 		Description: req.Description,
 		AvatarURL:   avatarURL,
 		InviteCode:  inviteCode,
-		Budget:      req.Budget,
 		EventDate:   eventDate,
 		OwnerID:     uid,
+
+		Timezone:     timezone,
+		JoinDeadline: joinDeadline,
+		DrawDeadline: drawDeadline,
+
+		OfficeAddress: officeAddress,
+		Questions:     questions,
+
+		RequiredFields: requiredFields,
+	}
+
+	if previous != nil {
+		group.SeriesID = previous.SeriesID
+	}
+
+	if budget != nil {
+		group.Budget = budget.String()
+		group.BudgetMin = budget.Min
+		group.BudgetMax = budget.Max
+		group.BudgetCurrency = &budget.Currency
 	}
 
 	if err := h.DB.Create(&group).Error; err != nil {
@@ -183,9 +395,9 @@ func (h *Handler) CreateRaffle(c *gin.Context) {
 	h.DB.Create(&member)
 
 	// Reload with members
-	h.DB.Preload("Members").Preload("Members.User").First(&group, group.ID)
+	h.DB.Preload("Members").Preload("Members.User").Preload("Members.WishlistItems").First(&group, group.ID)
 
-	c.JSON(http.StatusCreated, h.raffleToResponse(group, uid))
+	c.JSON(http.StatusCreated, h.raffleToResponse(group, uid, h.loadExchangeRates()))
 }
 
 func (h *Handler) GetRaffle(c *gin.Context) {
@@ -200,7 +412,7 @@ func (h *Handler) GetRaffle(c *gin.Context) {
 	}
 
 	var group models.Group
-	if err := h.DB.Preload("Members").Preload("Members.User").First(&group, gid).Error; err != nil {
+	if err := h.DB.Preload("Members").Preload("Members.User").Preload("Members.WishlistItems").First(&group, gid).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
 		return
 	}
@@ -219,7 +431,7 @@ func (h *Handler) GetRaffle(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, h.raffleToResponse(group, uid))
+	c.JSON(http.StatusOK, h.raffleToResponse(group, uid, h.loadExchangeRates()))
 }
 
 func (h *Handler) DeleteRaffle(c *gin.Context) {
@@ -244,8 +456,13 @@ func (h *Handler) DeleteRaffle(c *gin.Context) {
 		return
 	}
 
-	// Delete assignments, members, then group
+	// Delete assignments, wishlists, members, then group
 	h.DB.Where("group_id = ?", gid).Delete(&models.Assignment{})
+	h.DB.Where("group_id = ?", gid).Delete(&models.WishlistReservation{})
+	h.DB.Where("group_id = ?", gid).Delete(&models.GiftShipment{})
+	h.DB.Where("group_id = ?", gid).Delete(&models.ThankYouNote{})
+	h.DB.Where("group_id = ?", gid).Delete(&models.SantaGuess{})
+	h.DB.Where("member_id IN (?)", h.DB.Model(&models.Member{}).Select("id").Where("group_id = ?", gid)).Delete(&models.WishlistItem{})
 	h.DB.Where("group_id = ?", gid).Delete(&models.Member{})
 	h.DB.Delete(&group)
 
@@ -301,11 +518,28 @@ func (h *Handler) RemoveMember(c *gin.Context) {
 		return
 	}
 
-	// Delete all exclusions related to this member
-	h.DB.Where("group_id = ? AND (participant_a = ? OR participant_b = ?)", gid, mid, mid).Delete(&models.Exclusion{})
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// Delete all exclusions related to this member
+		if err := tx.Where("group_id = ? AND (participant_a = ? OR participant_b = ?)", gid, mid, mid).Delete(&models.Exclusion{}).Error; err != nil {
+			return err
+		}
+
+		// Delete reservations made by the member and reservations of the member's wishlist items
+		if err := tx.Where("santa_id = ? OR item_id IN (?)", mid,
+			tx.Model(&models.WishlistItem{}).Select("id").Where("member_id = ?", mid)).
+			Delete(&models.WishlistReservation{}).Error; err != nil {
+			return err
+		}
 
-	// Delete the member
-	if err := h.DB.Delete(&member).Error; err != nil {
+		// Delete the member's raffle wishlist
+		if err := tx.Where("member_id = ?", mid).Delete(&models.WishlistItem{}).Error; err != nil {
+			return err
+		}
+
+		// Delete the member
+		return tx.Delete(&member).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
 		return
 	}
@@ -337,6 +571,11 @@ func (h *Handler) JoinRaffle(c *gin.Context) {
 		return
 	}
 
+	if group.JoinDeadline != nil && time.Now().After(*group.JoinDeadline) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot join, join deadline has passed"})
+		return
+	}
+
 	// Check if already member
 	var existingMember models.Member
 	if err := h.DB.Where("group_id = ? AND user_id = ?", group.ID, uid).First(&existingMember).Error; err == nil {
@@ -347,6 +586,39 @@ func (h *Handler) JoinRaffle(c *gin.Context) {
 		return
 	}
 
+	// Необязательный выбор адреса и пресета профиля
+	var req JoinRaffleRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var preset *models.ProfilePreset
+	if req.PresetID != nil && *req.PresetID != "" {
+		p, err := h.findProfilePreset(uid, *req.PresetID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Profile preset not found"})
+			return
+		}
+		preset = &p
+		if req.AddressID == nil && p.AddressID != nil {
+			addressID := p.AddressID.String()
+			req.AddressID = &addressID
+		}
+	}
+
+	var saved *models.SavedAddress
+	if req.AddressID != nil && *req.AddressID != "" {
+		a, err := h.findSavedAddress(uid, *req.AddressID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			return
+		}
+		saved = &a
+	}
+
 	// Копируем профиль из UserProfile (если есть)
 	var userProfile models.UserProfile
 	member := models.Member{
@@ -370,6 +642,15 @@ func (h *Handler) JoinRaffle(c *gin.Context) {
 		member.RegionEn = userProfile.RegionEn
 		member.Wishlist = userProfile.Wishlist
 		member.AntiWishlist = userProfile.AntiWishlist
+		member.Timezone = userProfile.Timezone
+	}
+
+	// Выбранные пресет и адрес перекрывают основной профиль
+	if preset != nil {
+		applyProfilePreset(&member, preset)
+	}
+	if saved != nil {
+		applySavedAddress(&member, saved)
 	}
 
 	if err := h.DB.Create(&member).Error; err != nil {
@@ -377,10 +658,15 @@ func (h *Handler) JoinRaffle(c *gin.Context) {
 		return
 	}
 
+	// Копируем структурированный вишлист из профиля
+	if err := h.copyWishlistToMember(uid, member.ID); err != nil {
+		log.Printf("Failed to copy wishlist for member %s: %v", member.ID, err)
+	}
+
 	// Reload group with members
-	h.DB.Preload("Members").Preload("Members.User").First(&group, group.ID)
+	h.DB.Preload("Members").Preload("Members.User").Preload("Members.WishlistItems").First(&group, group.ID)
 
-	c.JSON(http.StatusOK, h.raffleToResponse(group, uid))
+	c.JSON(http.StatusOK, h.raffleToResponse(group, uid, h.loadExchangeRates()))
 }
 
 func (h *Handler) DrawNames(c *gin.Context) {
@@ -395,7 +681,7 @@ func (h *Handler) DrawNames(c *gin.Context) {
 	}
 
 	var group models.Group
-	if err := h.DB.Preload("Members").First(&group, gid).Error; err != nil {
+	if err := h.DB.Preload("Members").Preload("Members.User").Preload("Members.WishlistItems").First(&group, gid).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
 		return
 	}
@@ -416,11 +702,12 @@ func (h *Handler) DrawNames(c *gin.Context) {
 	}
 
 	// Check if all members have filled profiles
-	for _, m := range group.Members {
-		if !isProfileFilled(m) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "All participants must fill their profiles before drawing"})
-			return
-		}
+	if incomplete := incompleteProfiles(group); len(incomplete) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "All participants must fill their profiles before drawing",
+			"incomplete": incomplete,
+		})
+		return
 	}
 
 	// Load exclusions for this raffle
@@ -459,6 +746,11 @@ func (h *Handler) DrawNames(c *gin.Context) {
 	for giverMemberID, gifteeMemberID := range assignments {
 		giver := memberMap[giverMemberID]
 		giver.GifteeID = &gifteeMemberID
+		if giver.ParcelCode == nil {
+			if code, err := generateParcelCode(); err == nil {
+				giver.ParcelCode = &code
+			}
+		}
 		h.DB.Save(giver)
 
 		// Also save to Assignment table for backward compatibility
@@ -475,9 +767,9 @@ func (h *Handler) DrawNames(c *gin.Context) {
 	h.DB.Save(&group)
 
 	// Reload with members
-	h.DB.Preload("Members").Preload("Members.User").First(&group, gid)
+	h.DB.Preload("Members").Preload("Members.User").Preload("Members.WishlistItems").First(&group, gid)
 
-	c.JSON(http.StatusOK, h.raffleToResponse(group, uid))
+	c.JSON(http.StatusOK, h.raffleToResponse(group, uid, h.loadExchangeRates()))
 }
 
 func (h *Handler) GetMyAssignment(c *gin.Context) {
@@ -503,33 +795,102 @@ func (h *Handler) GetMyAssignment(c *gin.Context) {
 	})
 }
 
-// isProfileFilled проверяет, заполнен ли профиль участника
-func isProfileFilled(m models.Member) bool {
-	// Считаем профиль заполненным, если есть хотя бы одно из ключевых полей
-	hasAddress := m.AddressLine1 != nil && *m.AddressLine1 != "" &&
-		m.City != nil && *m.City != "" &&
-		m.Country != nil && *m.Country != ""
-	hasWishlist := m.Wishlist != nil && *m.Wishlist != ""
+// parseBudgetRequest извлекает бюджет из запроса на создание розыгрыша (nil - бюджет не задан)
+func parseBudgetRequest(req CreateRaffleRequest) (*currency.Budget, error) {
+	if req.BudgetMin != nil || req.BudgetMax != nil || req.BudgetCurrency != "" {
+		budget := currency.Budget{
+			Min:      req.BudgetMin,
+			Max:      req.BudgetMax,
+			Currency: strings.ToUpper(strings.TrimSpace(req.BudgetCurrency)),
+		}
+		if err := budget.Validate(); err != nil {
+			return nil, err
+		}
+		return &budget, nil
+	}
+
+	if strings.TrimSpace(req.Budget) == "" {
+		return nil, nil
+	}
 
-	return hasAddress || hasWishlist
+	budget, err := currency.ParseBudget(req.Budget)
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
 }
 
-func (h *Handler) raffleToResponse(g models.Group, currentUserID uuid.UUID) RaffleResponse {
+// raffleToResponse собирает ответ по розыгрышу; rates - курсы для пересчета бюджета (loadExchangeRates)
+func (h *Handler) raffleToResponse(g models.Group, currentUserID uuid.UUID, rates currency.Rates) RaffleResponse {
 	members := make([]MemberResponse, len(g.Members))
 	for i, m := range g.Members {
+		missingFields, missingQuestions := missingProfileFields(m, g)
 		members[i] = MemberResponse{
 			ID:              m.ID.String(),
 			UserID:          m.UserID.String(),
 			Name:            m.User.Name,
 			AvatarURL:       m.User.AvatarURL,
-			IsProfileFilled: isProfileFilled(m),
+			IsProfileFilled: len(missingFields) == 0 && len(missingQuestions) == 0,
+
+			MissingFields:    missingFields,
+			MissingQuestions: missingQuestions,
+		}
+	}
+
+	var currentMember *models.Member
+	for i := range g.Members {
+		if g.Members[i].UserID == currentUserID {
+			currentMember = &g.Members[i]
+			break
+		}
+	}
+
+	loc := raffleLocation(g)
+	formatTime := func(t *time.Time) *string {
+		if t == nil {
+			return nil
+		}
+		formatted := t.In(loc).Format(time.RFC3339)
+		return &formatted
+	}
+
+	// Пересчитываем бюджет в валюту страны текущего участника
+	var budgetLocal *BudgetResponse
+	if budget := groupBudget(g); budget != nil && currentMember != nil && currentMember.Country != nil {
+		localCurrency := currency.ForCountry(*currentMember.Country)
+		if localCurrency != "" && localCurrency != budget.Currency {
+			if converted, ok := rates.ConvertBudget(*budget, localCurrency); ok {
+				budgetLocal = &BudgetResponse{
+					Min:      converted.Min,
+					Max:      converted.Max,
+					Currency: converted.Currency,
+				}
+			}
 		}
 	}
 
-	var eventDate *string
-	if g.EventDate != nil {
-		formatted := g.EventDate.Format("2006-01-02")
-		eventDate = &formatted
+	// Отсчет считаем в часовом поясе участника (если не задан - в поясе розыгрыша)
+	var countdown *CountdownResponse
+	if currentMember != nil {
+		memberLoc := loc
+		if currentMember.Timezone != nil && validator.ValidateTimezone(*currentMember.Timezone) == nil {
+			if l, err := time.LoadLocation(*currentMember.Timezone); err == nil {
+				memberLoc = l
+			}
+		}
+		now := time.Now()
+		countdown = &CountdownResponse{
+			Timezone:     memberLoc.String(),
+			Event:        deadlineIn(g.EventDate, memberLoc, now),
+			JoinDeadline: deadlineIn(g.JoinDeadline, memberLoc, now),
+			DrawDeadline: deadlineIn(g.DrawDeadline, memberLoc, now),
+		}
+	}
+
+	var seriesID *string
+	if g.SeriesID != nil {
+		id := g.SeriesID.String()
+		seriesID = &id
 	}
 
 	return RaffleResponse{
@@ -539,12 +900,83 @@ func (h *Handler) raffleToResponse(g models.Group, currentUserID uuid.UUID) Raff
 		AvatarURL:   g.AvatarURL,
 		InviteCode:  g.InviteCode,
 		Budget:      g.Budget,
-		EventDate:   eventDate,
+		EventDate:   formatTime(g.EventDate),
 		IsDrawn:     g.IsDrawn,
 		IsOwner:     g.OwnerID == currentUserID,
 		OwnerID:     g.OwnerID.String(),
 		Members:     members,
 		CreatedAt:   g.CreatedAt.Format(time.RFC3339),
+
+		BudgetMin:      g.BudgetMin,
+		BudgetMax:      g.BudgetMax,
+		BudgetCurrency: g.BudgetCurrency,
+		BudgetLocal:    budgetLocal,
+
+		Timezone:     loc.String(),
+		JoinDeadline: formatTime(g.JoinDeadline),
+		DrawDeadline: formatTime(g.DrawDeadline),
+		Countdown:    countdown,
+
+		RevealedAt: formatTime(g.RevealedAt),
+		SeriesID:   seriesID,
+
+		OfficeAddress: g.OfficeAddress,
+		Questions:     g.Questions,
+
+		RequiredFields: g.RequiredFields,
+	}
+}
+
+// raffleLocation возвращает часовой пояс розыгрыша (UTC, если не задан или некорректен)
+func raffleLocation(g models.Group) *time.Location {
+	if g.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(g.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// parseRaffleTime разбирает момент времени: RFC3339 с явным смещением
+// либо локальное время розыгрыша ("2006-01-02T15:04", "2006-01-02")
+func parseRaffleTime(value string, loc *time.Location) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, errors.New("expected RFC3339 or YYYY-MM-DD[THH:MM]")
+}
+
+// deadlineIn считает отсчет до момента t в часовом поясе loc
+func deadlineIn(t *time.Time, loc *time.Location, now time.Time) *DeadlineResponse {
+	if t == nil {
+		return nil
+	}
+
+	local := t.In(loc)
+	nowLocal := now.In(loc)
+
+	// Календарные дни: сравниваем даты в полночь по местному времени
+	target := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	today := time.Date(nowLocal.Year(), nowLocal.Month(), nowLocal.Day(), 0, 0, 0, 0, time.UTC)
+
+	return &DeadlineResponse{
+		At:          local.Format(time.RFC3339),
+		DaysLeft:    int(target.Sub(today).Hours() / 24),
+		SecondsLeft: int64(t.Sub(now).Seconds()),
 	}
 }
 
@@ -561,7 +993,7 @@ func (h *Handler) GetMyProfile(c *gin.Context) {
 
 	// Найти участника
 	var member models.Member
-	if err := h.DB.Preload("User").Where("group_id = ? AND user_id = ?", rid, uid).First(&member).Error; err != nil {
+	if err := h.DB.Preload("User").Preload("Group").Where("group_id = ? AND user_id = ?", rid, uid).First(&member).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
 		return
 	}
@@ -582,6 +1014,16 @@ func (h *Handler) GetMyProfile(c *gin.Context) {
 		RegionEn:       member.RegionEn,
 		Wishlist:       member.Wishlist,
 		AntiWishlist:   member.AntiWishlist,
+		Timezone:       member.Timezone,
+		StayAnonymous:  member.StayAnonymous,
+
+		AddressPrivacy:    member.AddressPrivacy,
+		AddressRevealDays: member.AddressRevealDays,
+
+		Questions: member.Group.Questions,
+		Answers:   member.Answers,
+
+		AddressID: member.AddressID,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -604,6 +1046,13 @@ func (h *Handler) UpdateMyProfile(c *gin.Context) {
 		return
 	}
 
+	if req.Timezone != nil {
+		if err := validator.ValidateTimezone(*req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+			return
+		}
+	}
+
 	// Найти участника
 	var member models.Member
 	if err := h.DB.Where("group_id = ? AND user_id = ?", rid, uid).First(&member).Error; err != nil {
@@ -611,7 +1060,18 @@ func (h *Handler) UpdateMyProfile(c *gin.Context) {
 		return
 	}
 
+	// Проверка адреса по правилам страны и автозаполнение английских полей
+	if err := normalizeAddressFields(addressFields{
+		Line1: &req.AddressLine1, Line2: &req.AddressLine2, City: &req.City, Region: &req.Region,
+		PostalCode: &req.PostalCode, Country: &req.Country,
+		Line1En: &req.AddressLine1En, Line2En: &req.AddressLine2En, CityEn: &req.CityEn, RegionEn: &req.RegionEn,
+	}, localAddress(member.AddressLine1, member.AddressLine2, member.City, member.Region)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+		return
+	}
+
 	// Обновить профиль
+	before := member
 	member.Phone = req.Phone
 	member.About = req.About
 	member.AddressLine1 = req.AddressLine1
@@ -626,6 +1086,86 @@ func (h *Handler) UpdateMyProfile(c *gin.Context) {
 	member.RegionEn = req.RegionEn
 	member.Wishlist = req.Wishlist
 	member.AntiWishlist = req.AntiWishlist
+	member.Timezone = req.Timezone
+
+	// Пресет и адрес из адресной книги пользователя
+	if req.PresetID != nil && *req.PresetID != "" {
+		preset, err := h.findProfilePreset(uid, *req.PresetID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Profile preset not found"})
+			return
+		}
+		applyProfilePreset(&member, &preset)
+		if req.AddressID == nil && preset.AddressID != nil {
+			addressID := preset.AddressID.String()
+			req.AddressID = &addressID
+		}
+	}
+
+	var fromSaved []string
+	switch {
+	case req.AddressID == nil:
+		// Адрес поправлен вручную - больше не следим за записью адресной книги
+		if member.AddressID != nil && addressFieldsChanged(&before, &member) {
+			member.AddressID = nil
+		}
+	case *req.AddressID == "":
+		// Отвязка от адресной книги: адрес снова обновляется из основного профиля
+		if member.AddressID != nil {
+			member.AddressID = nil
+			clearAddressOverrides(&member)
+		}
+	default:
+		saved, err := h.findSavedAddress(uid, *req.AddressID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			return
+		}
+		applySavedAddress(&member, &saved)
+		for _, f := range savedAddressFields(&saved) {
+			fromSaved = append(fromSaved, f.Name)
+		}
+	}
+	markOverriddenFields(&before, &member, fromSaved...)
+
+	var group models.Group
+	if err := h.DB.First(&group, rid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	// После раскрытия анонимность уже не изменить
+	if req.StayAnonymous != nil && *req.StayAnonymous != member.StayAnonymous {
+		if group.RevealedAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change anonymity after santas are revealed"})
+			return
+		}
+		member.StayAnonymous = *req.StayAnonymous
+	}
+
+	// Ответы на вопросы розыгрыша
+	if req.Answers != nil {
+		answers, err := validateAnswers(req.Answers, group.Questions)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+			return
+		}
+		member.Answers = answers
+	}
+
+	// Приватность адреса (не передана - оставляем как есть)
+	if req.AddressPrivacy != nil {
+		mode := addressPrivacyMode(*req.AddressPrivacy)
+		if err := validateAddressPrivacy(mode, req.AddressRevealDays, group); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+			return
+		}
+		member.AddressPrivacy = mode
+		member.AddressRevealDays = nil
+		if mode == models.AddressPrivacyDelayed {
+			member.AddressRevealDays = req.AddressRevealDays
+		}
+	}
 
 	if err := h.DB.Save(&member).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
@@ -696,7 +1236,24 @@ func (h *Handler) GetMyGiftee(c *gin.Context) {
 		RegionEn:       giftee.RegionEn,
 		Wishlist:       giftee.Wishlist,
 		AntiWishlist:   giftee.AntiWishlist,
+
+		AddressChangedAt:     giftee.AddressChangedAt,
+		AddressChangedFields: giftee.AddressChangedFields,
+	}
+	applyAddressPrivacy(&response, giftee, group, time.Now())
+	response.Answers = answersToResponse(giftee.Answers, group.Questions)
+
+	members := []models.Member{giftee}
+	h.ensureParcelCodes(members)
+	response.ParcelCode = members[0].ParcelCode
+
+	// Структурированный вишлист; по умолчанию скрываем позиции вне бюджета (?budget_filter=false - показать все)
+	var items []models.WishlistItem
+	h.DB.Where("member_id = ?", giftee.ID).Order("priority DESC, created_at ASC").Find(&items)
+	if budget := groupBudget(group); budget != nil && c.Query("budget_filter") != "false" {
+		items, response.HiddenWishlistItems = filterWishlistByBudget(items, *budget, h.loadExchangeRates())
 	}
+	response.WishlistItems = h.gifteeWishlistToResponse(items, member.ID)
 
 	c.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"secret-santa/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// GifteeWishlistItemResponse - позиция вишлиста получателя глазами дарителя
+type GifteeWishlistItemResponse struct {
+	WishlistItemResponse
+	Reserved     bool `json:"reserved"` // Забронирована кем-то из дарителей этого получателя
+	ReservedByMe bool `json:"reserved_by_me"`
+}
+
+// ReserveGifteeWishlistItem - тайно забронировать позицию вишлиста своего получателя
+func (h *Handler) ReserveGifteeWishlistItem(c *gin.Context) {
+	santa, item, ok := h.santaWishlistItem(c)
+	if !ok {
+		return
+	}
+
+	var existing models.WishlistReservation
+	if err := h.DB.Where("item_id = ?", item.ID).First(&existing).Error; err == nil {
+		if existing.SantaID == santa.ID {
+			c.JSON(http.StatusOK, gin.H{"message": "Item reserved"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Item is already reserved"})
+		return
+	}
+
+	reservation := models.WishlistReservation{
+		GroupID: santa.GroupID,
+		ItemID:  item.ID,
+		SantaID: santa.ID,
+	}
+
+	// Уникальный индекс по item_id защищает от одновременной брони двумя дарителями
+	if err := h.DB.Create(&reservation).Error; err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Item is already reserved"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reserve item"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Item reserved"})
+}
+
+// UnreserveGifteeWishlistItem - снять свою бронь с позиции вишлиста
+func (h *Handler) UnreserveGifteeWishlistItem(c *gin.Context) {
+	santa, item, ok := h.santaWishlistItem(c)
+	if !ok {
+		return
+	}
+
+	result := h.DB.Where("item_id = ? AND santa_id = ?", item.ID, santa.ID).Delete(&models.WishlistReservation{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove reservation"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reservation removed"})
+}
+
+// santaWishlistItem находит дарителя (текущий пользователь) и позицию вишлиста его получателя
+func (h *Handler) santaWishlistItem(c *gin.Context) (models.Member, models.WishlistItem, bool) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return models.Member{}, models.WishlistItem{}, false
+	}
+
+	itemID, err := uuid.Parse(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return models.Member{}, models.WishlistItem{}, false
+	}
+
+	var santa models.Member
+	if err := h.DB.Where("group_id = ? AND user_id = ?", rid, uid).First(&santa).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
+		return models.Member{}, models.WishlistItem{}, false
+	}
+
+	if santa.GifteeID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Draw not yet conducted"})
+		return models.Member{}, models.WishlistItem{}, false
+	}
+
+	// Бронировать можно только позиции своего получателя
+	var item models.WishlistItem
+	if err := h.DB.Where("id = ? AND member_id = ?", itemID, *santa.GifteeID).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist item not found"})
+		return models.Member{}, models.WishlistItem{}, false
+	}
+
+	return santa, item, true
+}
+
+// gifteeWishlistToResponse добавляет к позициям превью ссылок и статус брони для дарителя santaID
+func (h *Handler) gifteeWishlistToResponse(items []models.WishlistItem, santaID uuid.UUID) []GifteeWishlistItemResponse {
+	reservedBy := make(map[uuid.UUID]uuid.UUID)
+	if len(items) > 0 {
+		itemIDs := make([]uuid.UUID, len(items))
+		for i, item := range items {
+			itemIDs[i] = item.ID
+		}
+
+		var reservations []models.WishlistReservation
+		h.DB.Where("item_id IN ?", itemIDs).Find(&reservations)
+		for _, r := range reservations {
+			reservedBy[r.ItemID] = r.SantaID
+		}
+	}
+
+	previews := h.linkPreviewsFor(items)
+
+	response := make([]GifteeWishlistItemResponse, len(items))
+	for i, item := range items {
+		owner, reserved := reservedBy[item.ID]
+		response[i] = GifteeWishlistItemResponse{
+			WishlistItemResponse: wishlistItemToResponse(item),
+			Reserved:             reserved,
+			ReservedByMe:         reserved && owner == santaID,
+		}
+		if item.URL != nil {
+			response[i].Preview = previews[*item.URL]
+		}
+	}
+	return response
+}
+
+// isUniqueViolation - ошибка Postgres о нарушении уникального индекса (23505)
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"secret-santa/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RevealEvent - событие для участников: дарители раскрыты
+type RevealEvent struct {
+	Type       string    `json:"type"` // "revealed"
+	RevealedAt time.Time `json:"revealed_at"`
+}
+
+// RevealedSantaResponse - даритель, раскрытый получателю
+type RevealedSantaResponse struct {
+	Name      string  `json:"name"`
+	AvatarURL *string `json:"avatar_url"`
+}
+
+// ChainMemberResponse - участник в цепочке подарков
+type ChainMemberResponse struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	AvatarURL *string `json:"avatarUrl"`
+}
+
+// ChainLinkResponse - звено цепочки: кто кому дарил
+type ChainLinkResponse struct {
+	Santa  *ChainMemberResponse `json:"santa"` // nil - даритель решил остаться анонимным
+	Giftee ChainMemberResponse  `json:"giftee"`
+}
+
+// RevealRaffle - организатор раскрывает дарителей досрочно (иначе это произойдет в EventDate)
+func (h *Handler) RevealRaffle(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var group models.Group
+	if err := h.DB.First(&group, rid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	if group.OwnerID != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only raffle owner can reveal santas"})
+		return
+	}
+
+	if !group.IsDrawn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Draw not yet conducted"})
+		return
+	}
+
+	if group.RevealedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Santas already revealed"})
+		return
+	}
+
+	if err := h.revealRaffle(&group, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reveal santas"})
+		return
+	}
+
+	h.DB.Preload("Members").Preload("Members.User").Preload("Members.WishlistItems").First(&group, rid)
+	c.JSON(http.StatusOK, h.raffleToResponse(group, uid, h.loadExchangeRates()))
+}
+
+// GetGiftChain - полная цепочка подарков розыгрыша (после раскрытия)
+func (h *Handler) GetGiftChain(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var group models.Group
+	if err := h.DB.Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Members.User").First(&group, rid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	isMember := false
+	for _, m := range group.Members {
+		if m.UserID == uid {
+			isMember = true
+			break
+		}
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this raffle"})
+		return
+	}
+
+	if group.RevealedAt == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Santas have not been revealed yet"})
+		return
+	}
+
+	chain, ok := giftChain(group.Members)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Gift chain is hidden: it would reveal an anonymous santa"})
+		return
+	}
+	c.JSON(http.StatusOK, chain)
+}
+
+// RunRevealScheduler раскрывает дарителей в розыгрышах, у которых наступила EventDate
+func (h *Handler) RunRevealScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.revealDueRaffles()
+		<-ticker.C
+	}
+}
+
+// revealDueRaffles раскрывает все проведенные розыгрыши с прошедшей датой события
+func (h *Handler) revealDueRaffles() {
+	var groups []models.Group
+	if err := h.DB.Where("is_drawn = ? AND revealed_at IS NULL AND event_date <= ?", true, time.Now()).
+		Find(&groups).Error; err != nil {
+		log.Printf("Failed to load raffles to reveal: %v", err)
+		return
+	}
+
+	for i := range groups {
+		if err := h.revealRaffle(&groups[i], *groups[i].EventDate); err != nil {
+			log.Printf("Failed to reveal raffle %s: %v", groups[i].ID, err)
+		}
+	}
+}
+
+// revealRaffle фиксирует момент раскрытия и уведомляет подключенных участников
+func (h *Handler) revealRaffle(group *models.Group, at time.Time) error {
+	// Раскрытие и оценка догадок - одна транзакция: при ошибке оценки розыгрыш остается
+	// нераскрытым, и планировщик повторит попытку
+	revealed := false
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Условие на revealed_at защищает от повторного раскрытия параллельным запросом
+		result := tx.Model(&models.Group{}).
+			Where("id = ? AND revealed_at IS NULL", group.ID).
+			Update("revealed_at", at)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		revealed = true
+		return scoreGuesses(tx, group.ID, at)
+	})
+	if err != nil {
+		return err
+	}
+	group.RevealedAt = &at
+	if !revealed {
+		return nil
+	}
+
+	var members []models.Member
+	h.DB.Where("group_id = ?", group.ID).Find(&members)
+	for _, m := range members {
+		h.Hub.NotifyMember(group.ID, m.ID, RevealEvent{Type: "revealed", RevealedAt: at})
+	}
+	return nil
+}
+
+// revealedSanta возвращает данные дарителя, если розыгрыш раскрыт и даритель не остался анонимным
+func (h *Handler) revealedSanta(santa models.Member) *RevealedSantaResponse {
+	if santa.StayAnonymous {
+		return nil
+	}
+
+	var group models.Group
+	if err := h.DB.Select("revealed_at").First(&group, santa.GroupID).Error; err != nil || group.RevealedAt == nil {
+		return nil
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, santa.UserID).Error; err != nil {
+		return nil
+	}
+
+	return &RevealedSantaResponse{
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+	}
+}
+
+// giftChain строит цепочку подарков, обходя циклы жеребьевки.
+// Если кто-то из дарителей остался анонимным, звенья отдаются без порядка цикла (по имени получателя),
+// а у получателей анонимных дарителей даритель не указан. Зная всех участников, по такому ответу можно
+// сузить дарителя до множества анонимных, поэтому цепочка отдается, только если ни одного
+// анонимного дарителя нельзя вычислить однозначно (ok = false - цепочку показывать нельзя).
+func giftChain(members []models.Member) ([]ChainLinkResponse, bool) {
+	byID := make(map[uuid.UUID]*models.Member, len(members))
+	santaOf := make(map[uuid.UUID]*models.Member, len(members))
+	var anonymous []anonymousLink
+	for i := range members {
+		m := &members[i]
+		byID[m.ID] = m
+		if m.GifteeID != nil {
+			santaOf[*m.GifteeID] = m
+			if m.StayAnonymous {
+				anonymous = append(anonymous, anonymousLink{santa: m.ID, giftee: *m.GifteeID})
+			}
+		}
+	}
+
+	if len(anonymous) > 0 && !anonymousLinksAmbiguous(anonymous) {
+		return nil, false
+	}
+
+	toChainMember := func(m *models.Member) ChainMemberResponse {
+		return ChainMemberResponse{
+			ID:        m.ID.String(),
+			Name:      m.User.Name,
+			AvatarURL: m.User.AvatarURL,
+		}
+	}
+
+	links := make([]ChainLinkResponse, 0, len(members))
+	visited := make(map[uuid.UUID]bool, len(members))
+	for i := range members {
+		// Идем от каждого еще не посещенного участника по цепочке santa -> giftee
+		for m := &members[i]; m != nil && !visited[m.ID] && m.GifteeID != nil; {
+			visited[m.ID] = true
+			giftee, ok := byID[*m.GifteeID]
+			if !ok {
+				break
+			}
+
+			link := ChainLinkResponse{Giftee: toChainMember(giftee)}
+			if santa := santaOf[giftee.ID]; santa != nil && !santa.StayAnonymous {
+				santaResponse := toChainMember(santa)
+				link.Santa = &santaResponse
+			}
+			links = append(links, link)
+			m = giftee
+		}
+	}
+
+	if len(anonymous) > 0 {
+		// Порядок обхода цикла выдал бы соседей анонимного дарителя
+		sort.Slice(links, func(i, j int) bool {
+			if links[i].Giftee.Name != links[j].Giftee.Name {
+				return links[i].Giftee.Name < links[j].Giftee.Name
+			}
+			return links[i].Giftee.ID < links[j].Giftee.ID
+		})
+	}
+	return links, true
+}
+
+// anonymousLink - звено с анонимным дарителем
+type anonymousLink struct {
+	santa, giftee uuid.UUID
+}
+
+// anonymousLinksAmbiguous проверяет, что ни одно анонимное звено нельзя восстановить по цепочке.
+// Из ответа известны множество анонимных дарителей и множество их получателей; совместимые с ним
+// назначения - все паросочетания между ними без подарков самому себе. Звено скрыто, если есть
+// такое паросочетание, в котором у этого получателя другой даритель.
+func anonymousLinksAmbiguous(links []anonymousLink) bool {
+	for _, excluded := range links {
+		if !hasPerfectMatching(links, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPerfectMatching ищет паросочетание анонимных дарителей с их получателями (алгоритм Куна),
+// не использующее звено excluded и подарки самому себе
+func hasPerfectMatching(links []anonymousLink, excluded anonymousLink) bool {
+	santaOf := make(map[uuid.UUID]uuid.UUID, len(links)) // получатель -> даритель в текущем паросочетании
+
+	var try func(santa uuid.UUID, seen map[uuid.UUID]bool) bool
+	try = func(santa uuid.UUID, seen map[uuid.UUID]bool) bool {
+		for _, l := range links {
+			giftee := l.giftee
+			if giftee == santa || seen[giftee] || (santa == excluded.santa && giftee == excluded.giftee) {
+				continue
+			}
+			seen[giftee] = true
+			if current, taken := santaOf[giftee]; !taken || try(current, seen) {
+				santaOf[giftee] = santa
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, l := range links {
+		if !try(l.santa, make(map[uuid.UUID]bool, len(links))) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"secret-santa/internal/models"
+)
+
+// drawMembers собирает участников розыгрыша: giftees[i] - индекс получателя i-го участника
+func drawMembers(giftees []int, anonymous map[int]bool) []models.Member {
+	members := make([]models.Member, len(giftees))
+	for i := range members {
+		members[i].ID = uuid.New()
+		members[i].User.Name = fmt.Sprintf("member-%d", i)
+		members[i].StayAnonymous = anonymous[i]
+	}
+	for i, g := range giftees {
+		gifteeID := members[g].ID
+		members[i].GifteeID = &gifteeID
+	}
+	return members
+}
+
+// possibleSantas перебирает все жеребьевки, совместимые с цепочкой (названные дарители на месте,
+// никто не дарит себе), и для каждого получателя без дарителя собирает всех возможных дарителей
+func possibleSantas(members []models.Member, chain []ChainLinkResponse) map[string]map[string]bool {
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.ID.String()
+	}
+
+	known := make(map[string]string) // получатель -> названный даритель
+	for _, link := range chain {
+		if link.Santa != nil {
+			known[link.Giftee.ID] = link.Santa.ID
+		}
+	}
+
+	possible := make(map[string]map[string]bool)
+	santaOf := make(map[string]string)
+	used := make(map[string]bool)
+
+	var assign func(i int)
+	assign = func(i int) {
+		if i == len(ids) {
+			for giftee, santa := range santaOf {
+				if _, named := known[giftee]; !named {
+					if possible[giftee] == nil {
+						possible[giftee] = make(map[string]bool)
+					}
+					possible[giftee][santa] = true
+				}
+			}
+			return
+		}
+		giftee := ids[i]
+		for _, santa := range ids {
+			if santa == giftee || used[santa] {
+				continue
+			}
+			if named, ok := known[giftee]; ok && named != santa {
+				continue
+			}
+			used[santa], santaOf[giftee] = true, santa
+			assign(i + 1)
+			used[santa] = false
+			delete(santaOf, giftee)
+		}
+	}
+	assign(0)
+	return possible
+}
+
+// deducible сообщает, вычисляется ли однозначно хотя бы один анонимный даритель
+func deducible(members []models.Member) bool {
+	var links []anonymousLink
+	for _, m := range members {
+		if m.StayAnonymous {
+			links = append(links, anonymousLink{santa: m.ID, giftee: *m.GifteeID})
+		}
+	}
+	// Без цепочки знаем столько же, сколько из ответа с анонимными звеньями без дарителя
+	var chain []ChainLinkResponse
+	for _, m := range members {
+		giftee := ChainMemberResponse{ID: m.GifteeID.String()}
+		link := ChainLinkResponse{Giftee: giftee}
+		if !m.StayAnonymous {
+			link.Santa = &ChainMemberResponse{ID: m.ID.String()}
+		}
+		chain = append(chain, link)
+	}
+	for _, santas := range possibleSantas(members, chain) {
+		if len(santas) < 2 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGiftChainWithoutAnonymous(t *testing.T) {
+	members := drawMembers([]int{1, 2, 0}, nil)
+
+	chain, ok := giftChain(members)
+	if !ok {
+		t.Fatal("giftChain() refused a chain without anonymous santas")
+	}
+	if len(chain) != 3 {
+		t.Fatalf("len(chain) = %d, want 3", len(chain))
+	}
+	// Порядок цикла: получатель звена дарит следующему
+	for i, link := range chain {
+		next := chain[(i+1)%len(chain)]
+		if link.Santa == nil || next.Santa == nil || next.Santa.ID != link.Giftee.ID {
+			t.Fatalf("chain is not in cycle order: %+v", chain)
+		}
+	}
+}
+
+func TestGiftChainRefusesDeducibleAnonymous(t *testing.T) {
+	tests := map[string]struct {
+		giftees   []int
+		anonymous map[int]bool
+	}{
+		// Единственный анонимный даритель - единственный, кого нет среди названных
+		"single anonymous": {[]int{1, 2, 3, 0}, map[int]bool{2: true}},
+		// Двое дарят друг другу: себе дарить нельзя, значит звенья однозначны
+		"anonymous pair": {[]int{1, 0, 3, 2}, map[int]bool{0: true, 1: true}},
+	}
+
+	for name, tt := range tests {
+		if _, ok := giftChain(drawMembers(tt.giftees, tt.anonymous)); ok {
+			t.Errorf("%s: giftChain() returned a chain that reveals an anonymous santa", name)
+		}
+	}
+}
+
+func TestGiftChainHidesAnonymousSantas(t *testing.T) {
+	// Все жеребьевки из 5 участников (без подарков себе) при всех наборах анонимных дарителей
+	const n = 5
+	var draws [][]int
+	var permute func(p []int, i int)
+	permute = func(p []int, i int) {
+		if i == n {
+			for j, g := range p {
+				if j == g {
+					return
+				}
+			}
+			draws = append(draws, append([]int(nil), p...))
+			return
+		}
+		for j := i; j < n; j++ {
+			p[i], p[j] = p[j], p[i]
+			permute(p, i+1)
+			p[i], p[j] = p[j], p[i]
+		}
+	}
+	permute([]int{0, 1, 2, 3, 4}, 0)
+
+	for _, giftees := range draws {
+		for mask := 1; mask < 1<<n; mask++ {
+			anonymous := make(map[int]bool)
+			for i := 0; i < n; i++ {
+				if mask&(1<<i) != 0 {
+					anonymous[i] = true
+				}
+			}
+			members := drawMembers(giftees, anonymous)
+
+			chain, ok := giftChain(members)
+			if !ok {
+				// Отказ допустим, только если анонимного дарителя действительно можно вычислить
+				if !deducible(members) {
+					t.Errorf("draw %v, anonymous %v: chain refused although nobody can be deduced", giftees, anonymous)
+				}
+				continue
+			}
+
+			if len(chain) != n {
+				t.Fatalf("draw %v, anonymous %v: len(chain) = %d, want %d", giftees, anonymous, len(chain), n)
+			}
+			for giftee, santas := range possibleSantas(members, chain) {
+				if len(santas) < 2 {
+					t.Errorf("draw %v, anonymous %v: santa of %s can be deduced from the chain", giftees, anonymous, giftee)
+				}
+			}
+			for _, link := range chain {
+				for _, m := range members {
+					if m.GifteeID.String() == link.Giftee.ID && m.StayAnonymous && link.Santa != nil {
+						t.Errorf("draw %v, anonymous %v: anonymous santa %s is named", giftees, anonymous, m.ID)
+					}
+				}
+			}
+		}
+	}
+}
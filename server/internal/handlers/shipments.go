@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"secret-santa/internal/models"
+	"secret-santa/internal/tracking"
+	"secret-santa/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// giftStatusOrder - порядок статусов подарка (даритель может двигаться только вперед)
+var giftStatusOrder = map[string]int{
+	models.GiftStatusNotStarted: 0,
+	models.GiftStatusPurchased:  1,
+	models.GiftStatusShipped:    2,
+	models.GiftStatusDelivered:  3,
+	models.GiftStatusReceived:   4,
+}
+
+// GiftStatusRequest - обновление статуса подарка дарителем
+type GiftStatusRequest struct {
+	Status         string  `json:"status" binding:"required"`
+	Carrier        *string `json:"carrier"`
+	TrackingNumber *string `json:"tracking_number"`
+}
+
+// OutgoingGiftResponse - статус подарка, который я отправляю
+type OutgoingGiftResponse struct {
+	Status         string     `json:"status"`
+	Carrier        *string    `json:"carrier"`
+	TrackingNumber *string    `json:"tracking_number"`
+	TrackingURL    *string    `json:"tracking_url"`
+	CarrierState   *string    `json:"carrier_state"`
+	PurchasedAt    *time.Time `json:"purchased_at"`
+	ShippedAt      *time.Time `json:"shipped_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	ReceivedAt     *time.Time `json:"received_at"`
+
+	ThankYou *ThankYouResponse `json:"thank_you"` // Благодарность получателя
+}
+
+// IncomingGiftResponse - статус подарка для получателя (без данных, раскрывающих дарителя)
+type IncomingGiftResponse struct {
+	Status      string     `json:"status"`
+	OnItsWay    bool       `json:"on_its_way"` // Подарок в пути (трек-номер и перевозчик не раскрываются)
+	ShippedAt   *time.Time `json:"shipped_at"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+	ReceivedAt  *time.Time `json:"received_at"`
+
+	ThankYou *ThankYouResponse `json:"thank_you"` // Моя благодарность дарителю
+}
+
+// GiftSummaryResponse - сводка по подаркам для организатора (без пар)
+type GiftSummaryResponse struct {
+	Total    int            `json:"total"`
+	ByStatus map[string]int `json:"by_status"`
+}
+
+// GetOutgoingGift - статус моего подарка получателю
+func (h *Handler) GetOutgoingGift(c *gin.Context) {
+	santa, ok := h.drawnSanta(c)
+	if !ok {
+		return
+	}
+
+	shipment := h.findGiftShipment(santa.GroupID, santa.ID, *santa.GifteeID)
+	response := outgoingGiftToResponse(shipment)
+	response.ThankYou = h.findThankYou(c.Request.Context(), santa.GroupID, santa.ID, *santa.GifteeID)
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateOutgoingGift - даритель обновляет статус подарка
+func (h *Handler) UpdateOutgoingGift(c *gin.Context) {
+	santa, ok := h.drawnSanta(c)
+	if !ok {
+		return
+	}
+
+	var req GiftStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	shipment, err := h.ensureGiftShipment(santa.GroupID, santa.ID, *santa.GifteeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update gift status"})
+		return
+	}
+	country := ""
+	if santa.Country != nil {
+		country = *santa.Country
+	}
+	if err := applySantaGiftStatus(&shipment, req, country); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Save(&shipment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update gift status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, outgoingGiftToResponse(shipment))
+}
+
+// GetIncomingGift - статус подарка, который отправили мне
+func (h *Handler) GetIncomingGift(c *gin.Context) {
+	giftee, santa, ok := h.gifteeWithSanta(c)
+	if !ok {
+		return
+	}
+
+	shipment := h.findGiftShipment(giftee.GroupID, santa.ID, giftee.ID)
+	response := incomingGiftToResponse(shipment)
+	response.ThankYou = h.findThankYou(c.Request.Context(), giftee.GroupID, santa.ID, giftee.ID)
+	c.JSON(http.StatusOK, response)
+}
+
+// ConfirmGiftReceived - получатель подтверждает, что подарок получен,
+// и может оставить дарителю записку и фото подарка
+func (h *Handler) ConfirmGiftReceived(c *gin.Context) {
+	giftee, santa, ok := h.gifteeWithSanta(c)
+	if !ok {
+		return
+	}
+
+	note, err := bindThankYou(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	photoKey, status, err := h.uploadThankYouPhoto(c, giftee.GroupID)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	shipment, err := h.ensureGiftShipment(giftee.GroupID, santa.ID, giftee.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update gift status"})
+		return
+	}
+
+	// Подтвердить можно из любого статуса, в том числе not_started: подарок могли вручить лично,
+	// а даритель не отмечал покупку и отправку. Условие на статус делает повторное подтверждение пустым.
+	result := h.DB.Model(&models.GiftShipment{}).
+		Where("id = ? AND status <> ?", shipment.ID, models.GiftStatusReceived).
+		Updates(map[string]interface{}{"status": models.GiftStatusReceived, "received_at": time.Now()})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update gift status"})
+		return
+	}
+	changed := result.RowsAffected > 0
+	if err := h.DB.First(&shipment, "id = ?", shipment.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update gift status"})
+		return
+	}
+
+	response := incomingGiftToResponse(shipment)
+	if note != nil || photoKey != nil {
+		thanks, err := h.saveThankYouNote(giftee.GroupID, santa.ID, giftee.ID, note, photoKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save thank-you note"})
+			return
+		}
+		thanksResponse := h.thankYouToResponse(c.Request.Context(), thanks)
+		response.ThankYou = &thanksResponse
+		changed = true
+	} else {
+		response.ThankYou = h.findThankYou(c.Request.Context(), giftee.GroupID, santa.ID, giftee.ID)
+	}
+
+	// Сообщаем дарителю, если он сейчас в сети
+	if changed {
+		h.Hub.NotifyMember(santa.GroupID, santa.ID, GiftReceivedEvent{
+			Type:       "gift_received",
+			ReceivedAt: shipment.ReceivedAt,
+			ThankYou:   response.ThankYou,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetGiftSummary - сводка по статусам подарков для организатора (без информации о парах)
+func (h *Handler) GetGiftSummary(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var group models.Group
+	if err := h.DB.First(&group, rid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	if group.OwnerID != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only raffle owner can view gift summary"})
+		return
+	}
+
+	if !group.IsDrawn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Draw not yet conducted"})
+		return
+	}
+
+	// Количество пар = количество дарителей с назначенным получателем
+	var total int64
+	h.DB.Model(&models.Member{}).Where("group_id = ? AND giftee_id IS NOT NULL", rid).Count(&total)
+
+	var counts []struct {
+		Status string
+		Count  int
+	}
+	h.DB.Model(&models.GiftShipment{}).
+		Select("status, COUNT(*) AS count").
+		Where("group_id = ?", rid).
+		Group("status").
+		Scan(&counts)
+
+	byStatus := make(map[string]int, len(giftStatusOrder))
+	for status := range giftStatusOrder {
+		byStatus[status] = 0
+	}
+	tracked := 0
+	for _, row := range counts {
+		byStatus[row.Status] = row.Count
+		tracked += row.Count
+	}
+	// Пары без записи еще не начаты
+	byStatus[models.GiftStatusNotStarted] += int(total) - tracked
+
+	c.JSON(http.StatusOK, GiftSummaryResponse{
+		Total:    int(total),
+		ByStatus: byStatus,
+	})
+}
+
+// drawnSanta находит участника-дарителя текущего пользователя (после жеребьевки)
+func (h *Handler) drawnSanta(c *gin.Context) (models.Member, bool) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return models.Member{}, false
+	}
+
+	var santa models.Member
+	if err := h.DB.Where("group_id = ? AND user_id = ?", rid, uid).First(&santa).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
+		return models.Member{}, false
+	}
+
+	if santa.GifteeID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Draw not yet conducted"})
+		return models.Member{}, false
+	}
+
+	return santa, true
+}
+
+// gifteeWithSanta находит участника текущего пользователя и его дарителя
+func (h *Handler) gifteeWithSanta(c *gin.Context) (models.Member, models.Member, bool) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return models.Member{}, models.Member{}, false
+	}
+
+	var giftee models.Member
+	if err := h.DB.Where("group_id = ? AND user_id = ?", rid, uid).First(&giftee).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
+		return models.Member{}, models.Member{}, false
+	}
+
+	var santa models.Member
+	if err := h.DB.Where("group_id = ? AND giftee_id = ?", rid, giftee.ID).First(&santa).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Draw has not been performed yet or you don't have a santa"})
+		return models.Member{}, models.Member{}, false
+	}
+
+	return giftee, santa, true
+}
+
+// findGiftShipment возвращает запись о подарке пары (несохраненную новую, если ее еще нет)
+func (h *Handler) findGiftShipment(groupID, santaID, gifteeID uuid.UUID) models.GiftShipment {
+	shipment := models.GiftShipment{
+		GroupID:  groupID,
+		SantaID:  santaID,
+		GifteeID: gifteeID,
+		Status:   models.GiftStatusNotStarted,
+	}
+	h.DB.Where("group_id = ? AND santa_id = ? AND giftee_id = ?", groupID, santaID, gifteeID).First(&shipment)
+	return shipment
+}
+
+// ensureGiftShipment возвращает запись о подарке пары, создавая ее при первом изменении.
+// Параллельные первые запросы не конфликтуют: вставка без ошибки пропускается, если запись уже есть.
+func (h *Handler) ensureGiftShipment(groupID, santaID, gifteeID uuid.UUID) (models.GiftShipment, error) {
+	shipment := models.GiftShipment{
+		GroupID:  groupID,
+		SantaID:  santaID,
+		GifteeID: gifteeID,
+		Status:   models.GiftStatusNotStarted,
+	}
+	if err := h.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&shipment).Error; err != nil {
+		return shipment, err
+	}
+	err := h.DB.Where("group_id = ? AND santa_id = ? AND giftee_id = ?", groupID, santaID, gifteeID).First(&shipment).Error
+	return shipment, err
+}
+
+// applySantaGiftStatus применяет изменение статуса от дарителя.
+// country - страна дарителя, нужна для распознавания перевозчика по трек-номеру.
+func applySantaGiftStatus(shipment *models.GiftShipment, req GiftStatusRequest, country string) error {
+	status := strings.TrimSpace(req.Status)
+	order, ok := giftStatusOrder[status]
+	if !ok {
+		return errors.New("invalid status")
+	}
+
+	if status == models.GiftStatusReceived {
+		return errors.New("only the giftee can confirm receipt")
+	}
+
+	if shipment.Status == models.GiftStatusReceived {
+		return errors.New("gift has already been received")
+	}
+
+	if order < giftStatusOrder[shipment.Status] {
+		return errors.New("gift status cannot go backwards")
+	}
+
+	if req.Carrier != nil {
+		carrier := strings.ToLower(validator.SanitizeString(*req.Carrier))
+		if err := validator.ValidateProfileField("carrier", carrier, validator.MaxCarrierLength); err != nil {
+			return err
+		}
+		shipment.Carrier = &carrier
+	}
+
+	if req.TrackingNumber != nil {
+		number := tracking.Normalize(validator.SanitizeString(*req.TrackingNumber))
+		if err := validator.ValidateTrackingNumber(number); err != nil {
+			return err
+		}
+		shipment.TrackingNumber = &number
+		shipment.CarrierState = nil
+		shipment.CarrierCheckedAt = nil
+
+		// Перевозчик не указан - распознаем по формату номера
+		if req.Carrier == nil || *req.Carrier == "" {
+			if carrier, ok := tracking.Detect(number, country); ok {
+				shipment.Carrier = &carrier
+			}
+		}
+	}
+
+	if status == models.GiftStatusShipped && (shipment.TrackingNumber == nil || *shipment.TrackingNumber == "") &&
+		(shipment.Carrier == nil || *shipment.Carrier == "") {
+		return errors.New("carrier or tracking number is required for shipped gifts")
+	}
+
+	// Отмечаем время каждого пройденного этапа (в т.ч. пропущенных)
+	now := time.Now()
+	stamps := []struct {
+		status string
+		at     **time.Time
+	}{
+		{models.GiftStatusPurchased, &shipment.PurchasedAt},
+		{models.GiftStatusShipped, &shipment.ShippedAt},
+		{models.GiftStatusDelivered, &shipment.DeliveredAt},
+	}
+	for _, stamp := range stamps {
+		if giftStatusOrder[stamp.status] <= order && *stamp.at == nil {
+			*stamp.at = &now
+		}
+	}
+
+	shipment.Status = status
+	return nil
+}
+
+func outgoingGiftToResponse(s models.GiftShipment) OutgoingGiftResponse {
+	var trackingURL *string
+	if s.Carrier != nil && s.TrackingNumber != nil {
+		if u := tracking.URL(*s.Carrier, *s.TrackingNumber); u != "" {
+			trackingURL = &u
+		}
+	}
+
+	return OutgoingGiftResponse{
+		Status:         s.Status,
+		Carrier:        s.Carrier,
+		TrackingNumber: s.TrackingNumber,
+		TrackingURL:    trackingURL,
+		CarrierState:   s.CarrierState,
+		PurchasedAt:    s.PurchasedAt,
+		ShippedAt:      s.ShippedAt,
+		DeliveredAt:    s.DeliveredAt,
+		ReceivedAt:     s.ReceivedAt,
+	}
+}
+
+func incomingGiftToResponse(s models.GiftShipment) IncomingGiftResponse {
+	return IncomingGiftResponse{
+		Status:      s.Status,
+		OnItsWay:    s.Status == models.GiftStatusShipped,
+		ShippedAt:   s.ShippedAt,
+		DeliveredAt: s.DeliveredAt,
+		ReceivedAt:  s.ReceivedAt,
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"secret-santa/internal/models"
+	"secret-santa/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Фото подарков хранятся в закрытой части бакета, как вложения чата: галерея доступна только участникам
+const (
+	maxThankYouPhotoSize = 5 * 1024 * 1024 // Максимальный размер фото подарка (как у аватара)
+	ThankYouPhotoURLTTL  = time.Hour       // Сколько живет ссылка на фото
+
+	thankYouPhotoPrefix = "private/thanks"
+)
+
+// ThankYouRequest - благодарность при подтверждении получения (JSON или multipart с полем photo)
+type ThankYouRequest struct {
+	Note *string `json:"note" form:"note"`
+}
+
+// ThankYouResponse - благодарность без данных об отправителе и получателе
+type ThankYouResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Note      *string   `json:"note"`
+	PhotoURL  *string   `json:"photo_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GiftReceivedEvent - событие для дарителя: получатель подтвердил получение подарка
+type GiftReceivedEvent struct {
+	Type       string            `json:"type"` // "gift_received"
+	ReceivedAt *time.Time        `json:"received_at"`
+	ThankYou   *ThankYouResponse `json:"thank_you"`
+}
+
+// GetThankYouGallery - все благодарности розыгрыша (доступны участникам после раскрытия)
+func (h *Handler) GetThankYouGallery(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return
+	}
+
+	var group models.Group
+	if err := h.DB.First(&group, rid).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Raffle not found"})
+		return
+	}
+
+	var count int64
+	h.DB.Model(&models.Member{}).Where("group_id = ? AND user_id = ?", rid, uid).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this raffle"})
+		return
+	}
+
+	if !thankYouGalleryOpen(group) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Thank-you gallery opens after santas are revealed"})
+		return
+	}
+
+	var notes []models.ThankYouNote
+	if err := h.DB.Where("group_id = ?", rid).Order("created_at ASC").Find(&notes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load thank-you notes"})
+		return
+	}
+
+	response := make([]ThankYouResponse, len(notes))
+	for i, n := range notes {
+		response[i] = h.thankYouToResponse(c.Request.Context(), n)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// thankYouGalleryOpen - галерея открывается вместе с раскрытием дарителей
+func thankYouGalleryOpen(g models.Group) bool {
+	return g.RevealedAt != nil
+}
+
+// bindThankYou читает записку и фото из запроса подтверждения получения.
+// Пустое тело допустимо: получение можно подтвердить без благодарности.
+func bindThankYou(c *gin.Context) (note *string, err error) {
+	if c.Request.ContentLength == 0 {
+		return nil, nil
+	}
+
+	var req ThankYouRequest
+	if err := c.ShouldBind(&req); err != nil {
+		return nil, err
+	}
+	if req.Note == nil {
+		return nil, nil
+	}
+
+	text := validator.SanitizeString(*req.Note)
+	if err := validator.ValidateProfileField("note", text, validator.MaxThankYouLength); err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return nil, nil
+	}
+	return &text, nil
+}
+
+// uploadThankYouPhoto загружает фото подарка из поля photo (если оно есть) и возвращает ключ в бакете
+func (h *Handler) uploadThankYouPhoto(c *gin.Context, groupID uuid.UUID) (*string, int, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return nil, 0, nil
+	}
+
+	file, err := c.FormFile("photo")
+	if err != nil {
+		// Фото необязательно
+		return nil, 0, nil
+	}
+
+	if file.Size > maxThankYouPhotoSize {
+		return nil, http.StatusBadRequest, errors.New("photo is too large (max 5MB)")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("failed to open photo")
+	}
+	defer src.Close()
+
+	// Тип определяем по содержимому, как у вложений чата
+	contentType, err := sniffChatAttachment(src)
+	if err != nil || !isChatImage(contentType) {
+		return nil, http.StatusBadRequest, errors.New("photo must be an image")
+	}
+
+	key, err := h.storage.UploadPrivate(c.Request.Context(), thankYouPhotoPrefix+"/"+groupID.String(), src,
+		"photo"+allowedChatAttachmentTypes[contentType], contentType)
+	if err != nil {
+		log.Printf("Failed to upload thank-you photo: %v", err)
+		return nil, http.StatusInternalServerError, errors.New("failed to upload photo")
+	}
+	return &key, 0, nil
+}
+
+// saveThankYouNote создает или обновляет благодарность пары (пустые поля не затирают сохраненные).
+// Замененное фото удаляется из бакета.
+func (h *Handler) saveThankYouNote(groupID, santaID, gifteeID uuid.UUID, note, photoKey *string) (models.ThankYouNote, error) {
+	thanks := models.ThankYouNote{
+		GroupID:  groupID,
+		SantaID:  santaID,
+		GifteeID: gifteeID,
+	}
+	h.DB.Where("group_id = ? AND santa_id = ? AND giftee_id = ?", groupID, santaID, gifteeID).First(&thanks)
+
+	if note != nil {
+		thanks.Note = note
+	}
+	oldPhoto := thanks.PhotoKey
+	if photoKey != nil {
+		thanks.PhotoKey = photoKey
+		thanks.PhotoURL = nil
+	}
+
+	if err := h.DB.Save(&thanks).Error; err != nil {
+		return thanks, err
+	}
+	if photoKey != nil && oldPhoto != nil && *oldPhoto != *photoKey {
+		if err := h.storage.Delete(context.Background(), *oldPhoto); err != nil {
+			log.Printf("Failed to delete replaced thank-you photo %s: %v", *oldPhoto, err)
+		}
+	}
+	return thanks, nil
+}
+
+// findThankYou возвращает благодарность пары или nil
+func (h *Handler) findThankYou(ctx context.Context, groupID, santaID, gifteeID uuid.UUID) *ThankYouResponse {
+	var thanks models.ThankYouNote
+	if err := h.DB.Where("group_id = ? AND santa_id = ? AND giftee_id = ?", groupID, santaID, gifteeID).
+		First(&thanks).Error; err != nil {
+		return nil
+	}
+	response := h.thankYouToResponse(ctx, thanks)
+	return &response
+}
+
+// thankYouToResponse собирает благодарность с временной ссылкой на фото
+func (h *Handler) thankYouToResponse(ctx context.Context, n models.ThankYouNote) ThankYouResponse {
+	response := ThankYouResponse{
+		ID:        n.ID,
+		Note:      n.Note,
+		PhotoURL:  n.PhotoURL,
+		CreatedAt: n.CreatedAt,
+	}
+	if n.PhotoKey != nil {
+		url, err := h.storage.PresignGet(ctx, *n.PhotoKey, ThankYouPhotoURLTTL, "")
+		if err != nil {
+			log.Printf("Failed to presign thank-you photo %s: %v", n.ID, err)
+		} else {
+			response.PhotoURL = &url
+		}
+	}
+	return response
+}
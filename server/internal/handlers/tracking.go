@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"secret-santa/internal/models"
+	"secret-santa/internal/tracking"
+)
+
+// trackingBatchSize - сколько отправлений опрашивать за один проход
+const trackingBatchSize = 100
+
+// RunTrackingPoller периодически запрашивает у перевозчиков статусы отправленных подарков
+func (h *Handler) RunTrackingPoller(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.pollShipments(interval)
+		<-ticker.C
+	}
+}
+
+// pollShipments обновляет статусы отправлений, которые давно не проверялись
+func (h *Handler) pollShipments(interval time.Duration) {
+	var shipments []models.GiftShipment
+	if err := h.DB.Where("status = ? AND carrier IS NOT NULL AND tracking_number IS NOT NULL", models.GiftStatusShipped).
+		Where("carrier_checked_at IS NULL OR carrier_checked_at < ?", time.Now().Add(-interval/2)).
+		Order("carrier_checked_at ASC NULLS FIRST").
+		Limit(trackingBatchSize).
+		Find(&shipments).Error; err != nil {
+		log.Printf("Failed to load shipments for tracking: %v", err)
+		return
+	}
+
+	for i := range shipments {
+		h.refreshShipmentTracking(&shipments[i])
+	}
+}
+
+// refreshShipmentTracking запрашивает статус одного отправления и сохраняет его
+func (h *Handler) refreshShipmentTracking(s *models.GiftShipment) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	status, err := h.Tracker.Track(ctx, *s.Carrier, *s.TrackingNumber)
+	now := time.Now()
+
+	if err != nil {
+		if err != tracking.ErrUnsupportedCarrier {
+			log.Printf("Failed to track shipment %s: %v", s.ID, err)
+		}
+		h.DB.Model(&models.GiftShipment{}).Where("id = ?", s.ID).Update("carrier_checked_at", now)
+		return
+	}
+
+	// Пока шел опрос пачки, получатель мог подтвердить получение - обновляем только
+	// поля перевозчика и только у отправлений, которые все еще в пути
+	if err := h.DB.Model(&models.GiftShipment{}).
+		Where("id = ? AND status = ?", s.ID, models.GiftStatusShipped).
+		Updates(trackingUpdates(status, now)).Error; err != nil {
+		log.Printf("Failed to save tracking status for shipment %s: %v", s.ID, err)
+	}
+}
+
+// trackingUpdates - изменения отправления по ответу перевозчика: доставленное
+// перевозчиком переходит в GiftStatusDelivered
+func trackingUpdates(status tracking.Status, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{
+		"carrier_state":      status.State,
+		"carrier_checked_at": now,
+	}
+	if status.State == tracking.StateDelivered {
+		deliveredAt := now
+		if !status.UpdatedAt.IsZero() {
+			deliveredAt = status.UpdatedAt
+		}
+		updates["status"] = models.GiftStatusDelivered
+		updates["delivered_at"] = deliveredAt
+	}
+	return updates
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"secret-santa/internal/models"
+	"secret-santa/internal/tracking"
+)
+
+func TestTrackingUpdates(t *testing.T) {
+	now := time.Date(2025, 12, 22, 12, 0, 0, 0, time.UTC)
+	carrierTime := time.Date(2025, 12, 21, 18, 30, 0, 0, time.UTC)
+
+	t.Run("in transit keeps status", func(t *testing.T) {
+		updates := trackingUpdates(tracking.Status{State: tracking.StateInTransit}, now)
+		if updates["carrier_state"] != tracking.StateInTransit || updates["carrier_checked_at"] != now {
+			t.Errorf("updates = %v", updates)
+		}
+		if _, ok := updates["status"]; ok {
+			t.Errorf("status changed for a parcel in transit: %v", updates)
+		}
+		if _, ok := updates["delivered_at"]; ok {
+			t.Errorf("delivered_at set for a parcel in transit: %v", updates)
+		}
+	})
+
+	t.Run("exception keeps status", func(t *testing.T) {
+		updates := trackingUpdates(tracking.Status{State: tracking.StateException}, now)
+		if _, ok := updates["status"]; ok {
+			t.Errorf("status changed for an exception: %v", updates)
+		}
+	})
+
+	t.Run("delivered uses carrier time", func(t *testing.T) {
+		updates := trackingUpdates(tracking.Status{State: tracking.StateDelivered, UpdatedAt: carrierTime}, now)
+		if updates["status"] != models.GiftStatusDelivered {
+			t.Errorf("status = %v, want %s", updates["status"], models.GiftStatusDelivered)
+		}
+		if updates["delivered_at"] != carrierTime {
+			t.Errorf("delivered_at = %v, want %v", updates["delivered_at"], carrierTime)
+		}
+	})
+
+	t.Run("delivered without carrier time", func(t *testing.T) {
+		updates := trackingUpdates(tracking.Status{State: tracking.StateDelivered}, now)
+		if updates["delivered_at"] != now {
+			t.Errorf("delivered_at = %v, want %v", updates["delivered_at"], now)
+		}
+	})
+
+	t.Run("never touches received", func(t *testing.T) {
+		// Подтверждение получателя защищено условием status = shipped в UPDATE;
+		// сами изменения не должны затрагивать поля, которые ставит получатель
+		updates := trackingUpdates(tracking.Status{State: tracking.StateDelivered}, now)
+		if _, ok := updates["received_at"]; ok {
+			t.Errorf("received_at in updates: %v", updates)
+		}
+		if updates["status"] == models.GiftStatusReceived {
+			t.Errorf("status = received: %v", updates)
+		}
+	})
+}
@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"secret-santa/internal/currency"
+	"secret-santa/internal/models"
+	"secret-santa/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxWishlistItems - максимальное количество позиций в одном вишлисте
+const MaxWishlistItems = 50
+
+// WishlistItemRequest - создание/обновление позиции вишлиста
+type WishlistItemRequest struct {
+	Title    string  `json:"title" binding:"required"`
+	URL      *string `json:"url"`
+	Price    *int64  `json:"price"`
+	Currency *string `json:"currency"`
+	Priority int     `json:"priority"` // 1 - низкий, 2 - средний (по умолчанию), 3 - высокий
+	Notes    *string `json:"notes"`
+}
+
+// WishlistItemResponse - позиция вишлиста
+type WishlistItemResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title"`
+	URL       *string   `json:"url"`
+	Price     *int64    `json:"price"`
+	Currency  *string   `json:"currency"`
+	Priority  int       `json:"priority"`
+	Notes     *string   `json:"notes"`
+	CreatedAt string    `json:"created_at"`
+
+	Preview *LinkPreviewResponse `json:"preview"` // Метаданные страницы по URL (загружаются в фоне)
+}
+
+// GetProfileWishlist - вишлист дефолтного профиля
+func (h *Handler) GetProfileWishlist(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	h.listWishlist(c, uid, nil)
+}
+
+// CreateProfileWishlistItem - добавить позицию в вишлист дефолтного профиля
+func (h *Handler) CreateProfileWishlistItem(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	h.createWishlistItem(c, uid, nil)
+}
+
+// UpdateProfileWishlistItem - обновить позицию вишлиста дефолтного профиля
+func (h *Handler) UpdateProfileWishlistItem(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	h.updateWishlistItem(c, uid, nil)
+}
+
+// DeleteProfileWishlistItem - удалить позицию вишлиста дефолтного профиля
+func (h *Handler) DeleteProfileWishlistItem(c *gin.Context) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	h.deleteWishlistItem(c, uid, nil)
+}
+
+// GetMyWishlist - вишлист участника в розыгрыше
+func (h *Handler) GetMyWishlist(c *gin.Context) {
+	uid, member, ok := h.wishlistMember(c)
+	if !ok {
+		return
+	}
+	h.listWishlist(c, uid, &member.ID)
+}
+
+// CreateMyWishlistItem - добавить позицию в вишлист участника
+func (h *Handler) CreateMyWishlistItem(c *gin.Context) {
+	uid, member, ok := h.wishlistMember(c)
+	if !ok {
+		return
+	}
+	h.createWishlistItem(c, uid, &member.ID)
+}
+
+// UpdateMyWishlistItem - обновить позицию вишлиста участника
+func (h *Handler) UpdateMyWishlistItem(c *gin.Context) {
+	uid, member, ok := h.wishlistMember(c)
+	if !ok {
+		return
+	}
+	h.updateWishlistItem(c, uid, &member.ID)
+}
+
+// DeleteMyWishlistItem - удалить позицию вишлиста участника
+func (h *Handler) DeleteMyWishlistItem(c *gin.Context) {
+	uid, member, ok := h.wishlistMember(c)
+	if !ok {
+		return
+	}
+	h.deleteWishlistItem(c, uid, &member.ID)
+}
+
+// wishlistMember находит участника текущего пользователя в розыгрыше из URL
+func (h *Handler) wishlistMember(c *gin.Context) (uuid.UUID, models.Member, bool) {
+	uid, _ := uuid.Parse(c.GetString("userID"))
+	rid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid raffle ID"})
+		return uuid.Nil, models.Member{}, false
+	}
+
+	var member models.Member
+	if err := h.DB.Where("group_id = ? AND user_id = ?", rid, uid).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this raffle"})
+		return uuid.Nil, models.Member{}, false
+	}
+
+	return uid, member, true
+}
+
+// wishlistScope ограничивает запрос вишлистом профиля (memberID = nil) или участника
+func wishlistScope(uid uuid.UUID, memberID *uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if memberID == nil {
+			return db.Where("user_id = ? AND member_id IS NULL", uid)
+		}
+		return db.Where("user_id = ? AND member_id = ?", uid, *memberID)
+	}
+}
+
+func (h *Handler) listWishlist(c *gin.Context, uid uuid.UUID, memberID *uuid.UUID) {
+	var items []models.WishlistItem
+	if err := h.DB.Scopes(wishlistScope(uid, memberID)).
+		Order("priority DESC, created_at ASC").
+		Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wishlist"})
+		return
+	}
+
+	response := wishlistToResponse(items)
+	previews := h.linkPreviewsFor(items)
+	for i, item := range items {
+		if item.URL != nil {
+			response[i].Preview = previews[*item.URL]
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) createWishlistItem(c *gin.Context, uid uuid.UUID, memberID *uuid.UUID) {
+	var req WishlistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := normalizeWishlistItem(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+		return
+	}
+
+	var count int64
+	h.DB.Model(&models.WishlistItem{}).Scopes(wishlistScope(uid, memberID)).Count(&count)
+	if count >= MaxWishlistItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many wishlist items"})
+		return
+	}
+
+	item := models.WishlistItem{
+		UserID:   uid,
+		MemberID: memberID,
+		Title:    req.Title,
+		URL:      req.URL,
+		Price:    req.Price,
+		Currency: req.Currency,
+		Priority: req.Priority,
+		Notes:    req.Notes,
+	}
+
+	if err := h.DB.Create(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create wishlist item"})
+		return
+	}
+
+	h.scheduleLinkPreview(item.URL)
+
+	c.JSON(http.StatusCreated, wishlistItemToResponse(item))
+}
+
+func (h *Handler) updateWishlistItem(c *gin.Context, uid uuid.UUID, memberID *uuid.UUID) {
+	itemID, err := uuid.Parse(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	var req WishlistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := normalizeWishlistItem(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+		return
+	}
+
+	var item models.WishlistItem
+	if err := h.DB.Scopes(wishlistScope(uid, memberID)).First(&item, "id = ?", itemID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist item not found"})
+		return
+	}
+
+	item.Title = req.Title
+	item.URL = req.URL
+	item.Price = req.Price
+	item.Currency = req.Currency
+	item.Priority = req.Priority
+	item.Notes = req.Notes
+
+	if err := h.DB.Save(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wishlist item"})
+		return
+	}
+
+	h.scheduleLinkPreview(item.URL)
+
+	c.JSON(http.StatusOK, wishlistItemToResponse(item))
+}
+
+func (h *Handler) deleteWishlistItem(c *gin.Context, uid uuid.UUID, memberID *uuid.UUID) {
+	itemID, err := uuid.Parse(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	result := h.DB.Scopes(wishlistScope(uid, memberID)).Where("id = ?", itemID).Delete(&models.WishlistItem{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete wishlist item"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist item not found"})
+		return
+	}
+
+	// Брони удаленной позиции больше не нужны
+	h.DB.Where("item_id = ?", itemID).Delete(&models.WishlistReservation{})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Wishlist item deleted"})
+}
+
+// normalizeWishlistItem санитизирует и проверяет позицию вишлиста
+func normalizeWishlistItem(req *WishlistItemRequest) error {
+	req.Title = validator.SanitizeString(req.Title)
+	if req.Title == "" {
+		return errors.New("title is required")
+	}
+	if err := validator.ValidateProfileField("title", req.Title, validator.MaxItemTitleLength); err != nil {
+		return err
+	}
+
+	if req.URL != nil {
+		trimmed := validator.SanitizeString(*req.URL)
+		if err := validator.ValidateURL(trimmed); err != nil {
+			return err
+		}
+		req.URL = &trimmed
+		if trimmed == "" {
+			req.URL = nil
+		}
+	}
+
+	if req.Notes != nil {
+		trimmed := validator.SanitizeString(*req.Notes)
+		if err := validator.ValidateProfileField("notes", trimmed, validator.MaxItemNotesLength); err != nil {
+			return err
+		}
+		req.Notes = &trimmed
+		if trimmed == "" {
+			req.Notes = nil
+		}
+	}
+
+	if req.Price != nil {
+		if *req.Price < 0 || *req.Price > currency.MaxAmount {
+			return errors.New("invalid price")
+		}
+		if req.Currency == nil || !currency.IsValid(*req.Currency) {
+			return errors.New("price requires a valid currency")
+		}
+		code := strings.ToUpper(*req.Currency)
+		req.Currency = &code
+	} else {
+		req.Currency = nil
+	}
+
+	if req.Priority == 0 {
+		req.Priority = models.WishlistPriorityMedium
+	}
+	if req.Priority < models.WishlistPriorityLow || req.Priority > models.WishlistPriorityHigh {
+		return errors.New("priority must be between 1 and 3")
+	}
+
+	return nil
+}
+
+// copyWishlistToMember копирует вишлист дефолтного профиля в розыгрыш
+func (h *Handler) copyWishlistToMember(uid, memberID uuid.UUID) error {
+	var items []models.WishlistItem
+	if err := h.DB.Scopes(wishlistScope(uid, nil)).Find(&items).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	copies := make([]models.WishlistItem, len(items))
+	for i, item := range items {
+		copies[i] = models.WishlistItem{
+			UserID:   uid,
+			MemberID: &memberID,
+			Title:    item.Title,
+			URL:      item.URL,
+			Price:    item.Price,
+			Currency: item.Currency,
+			Priority: item.Priority,
+			Notes:    item.Notes,
+		}
+	}
+	return h.DB.Create(&copies).Error
+}
+
+// filterWishlistByBudget скрывает позиции, цена которых вне бюджета розыгрыша.
+// Позиции без цены и с неизвестным курсом валюты остаются.
+func filterWishlistByBudget(items []models.WishlistItem, budget currency.Budget, rates currency.Rates) ([]models.WishlistItem, int) {
+	visible := make([]models.WishlistItem, 0, len(items))
+	hidden := 0
+	for _, item := range items {
+		if item.Price != nil && item.Currency != nil {
+			if price, ok := rates.Convert(*item.Price, *item.Currency, budget.Currency); ok && !budget.Contains(price) {
+				hidden++
+				continue
+			}
+		}
+		visible = append(visible, item)
+	}
+	return visible, hidden
+}
+
+func wishlistItemToResponse(item models.WishlistItem) WishlistItemResponse {
+	return WishlistItemResponse{
+		ID:        item.ID,
+		Title:     item.Title,
+		URL:       item.URL,
+		Price:     item.Price,
+		Currency:  item.Currency,
+		Priority:  item.Priority,
+		Notes:     item.Notes,
+		CreatedAt: item.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func wishlistToResponse(items []models.WishlistItem) []WishlistItemResponse {
+	response := make([]WishlistItemResponse, len(items))
+	for i, item := range items {
+		response[i] = wishlistItemToResponse(item)
+	}
+	return response
+}
@@ -43,6 +43,47 @@ type UserProfile struct {
 
 	Wishlist     *string   `gorm:"type:text" json:"wishlist"`      // Что хочу получить
 	AntiWishlist *string   `gorm:"type:text" json:"anti_wishlist"` // Аллергии, что не дарить
+	Timezone     *string   `json:"timezone"`                       // IANA часовой пояс, например "Europe/Moscow"
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SavedAddress - именованный адрес из адресной книги пользователя ("Дом", "Офис")
+type SavedAddress struct {
+	ID     uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Label  string    `gorm:"not null" json:"label"`
+	Phone  *string   `json:"phone"` // Телефон получателя по этому адресу
+
+	// Адрес на местном языке
+	AddressLine1 *string `json:"address_line1"`
+	AddressLine2 *string `json:"address_line2"`
+	City         *string `json:"city"`
+	Region       *string `json:"region"`
+	PostalCode   *string `json:"postal_code"`
+	Country      *string `json:"country"`
+
+	// Адрес на английском (автозаполняемый)
+	AddressLine1En *string `json:"address_line1_en"`
+	AddressLine2En *string `json:"address_line2_en"`
+	CityEn         *string `json:"city_en"`
+	RegionEn       *string `json:"region_en"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProfilePreset - именованный набор данных профиля ("Семья", "Работа"), который выбирается при вступлении в розыгрыш
+type ProfilePreset struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name      string     `gorm:"not null" json:"name"`
+	AddressID *uuid.UUID `gorm:"type:uuid" json:"address_id"` // Адрес из адресной книги
+
+	About        *string   `gorm:"type:text" json:"about"`
+	Wishlist     *string   `gorm:"type:text" json:"wishlist"`
+	AntiWishlist *string   `gorm:"type:text" json:"anti_wishlist"`
+	Timezone     *string   `json:"timezone"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -54,14 +95,40 @@ type Group struct {
 	Description string
 	AvatarURL   *string // URL аватара розыгрыша
 	InviteCode  string  `gorm:"uniqueIndex;not null"`
-	Budget      string
+	Budget      string  // Текстовое представление бюджета (для старых розыгрышей)
 	EventDate   *time.Time
-	OwnerID     uuid.UUID `gorm:"type:uuid;not null"`
-	Owner       User      `gorm:"foreignKey:OwnerID"`
-	IsDrawn     bool      `gorm:"default:false"`
-	Members     []Member
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+
+	// Часовой пояс розыгрыша (IANA) и дедлайны
+	Timezone     string `gorm:"not null;default:'UTC'"`
+	JoinDeadline *time.Time
+	DrawDeadline *time.Time
+
+	// Структурированный бюджет в целых единицах валюты ISO 4217
+	BudgetMin      *int64
+	BudgetMax      *int64
+	BudgetCurrency *string `gorm:"size:3"`
+
+	// Момент раскрытия дарителей (вручную организатором или автоматически в EventDate)
+	RevealedAt *time.Time
+
+	// Адрес офиса для самовывоза (участники с AddressPrivacyOffice получают подарки сюда)
+	OfficeAddress *string `gorm:"type:text"`
+
+	// Дополнительные вопросы анкеты участника (размер одежды, любимый цвет и т.п.)
+	Questions []ProfileQuestion `gorm:"serializer:json;type:jsonb"`
+
+	// Обязательные поля профиля (RequiredField*); пусто - адрес или вишлист
+	RequiredFields []string `gorm:"serializer:json;type:jsonb"`
+
+	// Серия повторяющихся розыгрышей (ID первого розыгрыша серии) - для общего рейтинга
+	SeriesID *uuid.UUID `gorm:"type:uuid;index"`
+
+	OwnerID   uuid.UUID `gorm:"type:uuid;not null"`
+	Owner     User      `gorm:"foreignKey:OwnerID"`
+	IsDrawn   bool      `gorm:"default:false"`
+	Members   []Member
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 // Member (Participant) - участник конкретного розыгрыша
@@ -92,14 +159,119 @@ type Member struct {
 
 	Wishlist     *string `gorm:"type:text" json:"wishlist"`
 	AntiWishlist *string `gorm:"type:text" json:"anti_wishlist"`
+	Timezone     *string `json:"timezone"`
+
+	// Адрес из адресной книги, выбранный для этого розыгрыша (правки адреса переносятся сюда)
+	AddressID *uuid.UUID `gorm:"type:uuid;index" json:"address_id"`
+
+	// Поля профиля, измененные в этом розыгрыше (не перезаписываются из UserProfile)
+	OverriddenFields []string `gorm:"serializer:json;type:jsonb" json:"overridden_fields"`
+
+	// Последняя смена адреса после жеребьевки - дарителю показывается, что адрес обновился
+	AddressChangedAt     *time.Time `json:"-"`
+	AddressChangedFields []string   `gorm:"serializer:json;type:jsonb" json:"-"`
+
+	// Ответы на вопросы розыгрыша: ID вопроса -> ответ
+	Answers map[string]string `gorm:"serializer:json;type:jsonb" json:"answers"`
+
+	// Структурированный вишлист участника в этом розыгрыше
+	WishlistItems []WishlistItem `gorm:"foreignKey:MemberID" json:"-"`
 
 	// Кому дарит (заполняется после жеребьевки)
 	GifteeID *uuid.UUID `gorm:"type:uuid" json:"giftee_id"`
 
+	// Анонимный код посылки для этого участника (пишется на посылке вместо имени дарителя)
+	ParcelCode *string `gorm:"uniqueIndex" json:"parcel_code"`
+
+	// Что дарителю можно знать об адресе (AddressPrivacy*)
+	AddressPrivacy    string `gorm:"not null;default:'full'" json:"address_privacy"`
+	AddressRevealDays *int   `json:"address_reveal_days"` // Для AddressPrivacyDelayed: за сколько дней до события открыть адрес
+
+	// Не раскрывать себя получателю даже после раскрытия розыгрыша
+	StayAnonymous bool `gorm:"not null;default:false" json:"stay_anonymous"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Поля профиля, которые организатор может сделать обязательными
+const (
+	RequiredFieldAddress   = "address"    // Адрес (не нужен при доставке в офис или лично в руки)
+	RequiredFieldAddressEn = "address_en" // Адрес на английском (для международной отправки)
+	RequiredFieldPhone     = "phone"
+	RequiredFieldWishlist  = "wishlist" // Текстовый или структурированный вишлист
+)
+
+// Типы вопросов анкеты розыгрыша
+const (
+	QuestionTypeText   = "text"
+	QuestionTypeChoice = "choice"
+	QuestionTypeNumber = "number"
+)
+
+// ProfileQuestion - вопрос анкеты, который организатор добавил в розыгрыш
+type ProfileQuestion struct {
+	ID       string   `json:"id"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Options  []string `json:"options,omitempty"` // Варианты для QuestionTypeChoice
+	Required bool     `json:"required"`
+}
+
+// Варианты приватности адреса участника
+const (
+	AddressPrivacyFull         = "full"          // Адрес и телефон доступны дарителю сразу после жеребьевки
+	AddressPrivacyOffice       = "office"        // Доставка в офис организатора
+	AddressPrivacyDelayed      = "delayed"       // Адрес откроется за N дней до события
+	AddressPrivacyHandDelivery = "hand_delivery" // Только лично в руки
+)
+
+// Приоритеты позиций вишлиста
+const (
+	WishlistPriorityLow    = 1
+	WishlistPriorityMedium = 2
+	WishlistPriorityHigh   = 3
+)
+
+// WishlistItem - позиция вишлиста: в дефолтном профиле (MemberID = nil) или в конкретном розыгрыше
+type WishlistItem struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	MemberID  *uuid.UUID `gorm:"type:uuid;index" json:"member_id"`
+	Title     string     `gorm:"not null" json:"title"`
+	URL       *string    `gorm:"type:text" json:"url"`
+	Price     *int64     `json:"price"` // В целых единицах валюты
+	Currency  *string    `gorm:"size:3" json:"currency"`
+	Priority  int        `gorm:"not null;default:2" json:"priority"`
+	Notes     *string    `gorm:"type:text" json:"notes"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// LinkPreview - кэш метаданных страницы по ссылке из вишлиста (Open Graph / JSON-LD)
+type LinkPreview struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	URLHash     string    `gorm:"size:64;uniqueIndex;not null" json:"-"` // sha256(URL) - URL может быть длинным
+	URL         string    `gorm:"type:text;not null" json:"url"`
+	Title       string    `gorm:"type:text" json:"title"`
+	Description string    `gorm:"type:text" json:"description"`
+	ImageURL    string    `gorm:"type:text" json:"image_url"`
+	SiteName    string    `json:"site_name"`
+	Price       string    `json:"price"`
+	Currency    string    `gorm:"size:3" json:"currency"`
+	Error       *string   `json:"-"` // Ошибка последней загрузки (негативный кэш)
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// WishlistReservation - тайная бронь позиции вишлиста дарителем (получатель ее не видит)
+type WishlistReservation struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	GroupID   uuid.UUID `gorm:"type:uuid;not null;index" json:"group_id"`
+	ItemID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"item_id"`
+	SantaID   uuid.UUID `gorm:"type:uuid;not null;index" json:"santa_id"` // Member ID дарителя
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // Exclusion - ограничение между участниками розыгрыша (кто не должен дарить кому)
 type Exclusion struct {
 	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
@@ -123,15 +295,109 @@ type Assignment struct {
 	CreatedAt  time.Time
 }
 
+// Статусы отправки подарка
+const (
+	GiftStatusNotStarted = "not_started"
+	GiftStatusPurchased  = "purchased"
+	GiftStatusShipped    = "shipped"
+	GiftStatusDelivered  = "delivered"
+	GiftStatusReceived   = "received" // Подтверждено получателем
+)
+
+// GiftShipment - статус подарка в паре даритель -> получатель
+type GiftShipment struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	GroupID        uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_gift_pair" json:"group_id"`
+	SantaID        uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_gift_pair" json:"santa_id"`  // Member ID дарителя
+	GifteeID       uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_gift_pair" json:"giftee_id"` // Member ID получателя
+	Status         string     `gorm:"not null;default:'not_started'" json:"status"`
+	Carrier        *string    `json:"carrier"`
+	TrackingNumber *string    `json:"tracking_number"`
+	PurchasedAt    *time.Time `json:"purchased_at"`
+
+	// Последний статус по данным перевозчика (tracking.State*)
+	CarrierState     *string    `json:"carrier_state"`
+	CarrierCheckedAt *time.Time `json:"carrier_checked_at"`
+
+	ShippedAt   *time.Time `json:"shipped_at"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+	ReceivedAt  *time.Time `json:"received_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ThankYouNote - благодарность получателя дарителю (записка и фото подарка)
+type ThankYouNote struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	GroupID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_thanks_pair" json:"group_id"`
+	SantaID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_thanks_pair" json:"santa_id"`  // Member ID дарителя
+	GifteeID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_thanks_pair" json:"giftee_id"` // Member ID получателя
+	Note      *string   `gorm:"type:text" json:"note"`
+	PhotoKey  *string   `gorm:"type:text" json:"-"`         // Фото в закрытой части бакета (ссылку выдает API)
+	PhotoURL  *string   `gorm:"type:text" json:"photo_url"` // Прямая ссылка на фото у старых записей (до PhotoKey)
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SantaGuess - догадка получателя о том, кто его даритель (игра "Угадай Санту")
+type SantaGuess struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	GroupID         uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_guess_giftee" json:"group_id"`
+	GifteeID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_guess_giftee" json:"giftee_id"` // Member ID угадывающего
+	GuessedMemberID uuid.UUID `gorm:"type:uuid;not null" json:"guessed_member_id"`
+
+	// Заполняется при раскрытии (nil - не оценивается: даритель остался анонимным)
+	Correct *bool `json:"correct"`
+	Points  int   `gorm:"not null;default:0" json:"points"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // Message - сообщение в анонимном чате между дарителем и получателем
 type Message struct {
 	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	GroupID   uuid.UUID  `gorm:"type:uuid;not null;index:idx_group_chat" json:"group_id"`
-	SantaID   uuid.UUID  `gorm:"type:uuid;not null;index:idx_santa_chat" json:"santa_id"`   // Member ID дарителя
-	GifteeID  uuid.UUID  `gorm:"type:uuid;not null;index:idx_giftee_chat" json:"giftee_id"` // Member ID получателя
-	FromSanta bool       `gorm:"not null" json:"from_santa"`                                // true = от дарителя, false = от получателя
+	GroupID   uuid.UUID  `gorm:"type:uuid;not null;index:idx_group_chat;index:idx_chat_history,priority:1" json:"group_id"`
+	SantaID   uuid.UUID  `gorm:"type:uuid;not null;index:idx_santa_chat;index:idx_chat_history,priority:2" json:"santa_id"`   // Member ID дарителя
+	GifteeID  uuid.UUID  `gorm:"type:uuid;not null;index:idx_giftee_chat;index:idx_chat_history,priority:3" json:"giftee_id"` // Member ID получателя
+	FromSanta bool       `gorm:"not null" json:"from_santa"`                                                                  // true = от дарителя, false = от получателя
 	Content   string     `gorm:"type:text;not null" json:"content"`
 	ReadAt    *time.Time `json:"read_at"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	EditedAt  *time.Time `json:"edited_at"`  // Последняя правка (история - MessageEdit)
+	DeletedAt *time.Time `json:"deleted_at"` // Не gorm.DeletedAt: удаленное сообщение остается в истории заглушкой без текста
+
+	// Вложение (фото или файл) в закрытом префиксе хранилища: ссылку на него получают только участники пары
+	AttachmentKey  *string `gorm:"size:255" json:"-"`               // Ключ объекта в бакете
+	AttachmentName *string `gorm:"type:text" json:"-"`              // Зашифрованное исходное имя файла
+	AttachmentType *string `gorm:"size:100" json:"attachment_type"` // MIME-тип
+	AttachmentSize *int64  `json:"attachment_size"`                 // Размер в байтах
+
+	CreatedAt time.Time `gorm:"index:idx_chat_history,priority:4" json:"created_at"` // Курсор истории чата
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MessageEdit - предыдущая версия отредактированного сообщения чата (зашифрована, видна только автору).
+// Удаляется вместе с текстом сообщения при его удалении.
+type MessageEdit struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	MessageID uuid.UUID `gorm:"type:uuid;not null;index" json:"message_id"`
+	Content   string    `gorm:"type:text;not null" json:"content"` // Зашифрованный текст до правки
+	CreatedAt time.Time `json:"created_at"`                        // Когда текст был заменен
+}
+
+// ChatTicket - одноразовый билет на подключение к WebSocket чата (вместо JWT в query-параметре,
+// который оседает в логах прокси). Хранится только хэш, билет удаляется при использовании.
+type ChatTicket struct {
+	TokenHash string    `gorm:"primaryKey;size:64" json:"-"` // sha256(билета)
+	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	GroupID   uuid.UUID `gorm:"type:uuid;not null" json:"group_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExchangeRate - курс валюты относительно базовой (currency.BaseCurrency)
+type ExchangeRate struct {
+	Currency  string    `gorm:"primaryKey;size:3" json:"currency"`
+	Rate      float64   `gorm:"not null" json:"rate"` // Сколько единиц валюты за 1 единицу базовой
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,172 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxPostgresPayload - ограничение Postgres на размер payload в NOTIFY (8000 байт минус запас)
+const MaxPostgresPayload = 7900
+
+// ErrPayloadTooLarge - сообщение не помещается в NOTIFY
+var ErrPayloadTooLarge = errors.New("pubsub payload too large")
+
+// Postgres - PubSub поверх LISTEN/NOTIFY: не требует отдельной инфраструктуры.
+// Публикация идет через общее соединение, каждая подписка держит свое выделенное соединение
+// и переподключается при обрыве: события за время переподключения теряются, о чем подписчик
+// узнает по отметке IsReconnected.
+type Postgres struct {
+	dsn string
+
+	mu     sync.Mutex
+	conn   *pgx.Conn // Соединение для публикации
+	cancel []context.CancelFunc
+	closed bool
+}
+
+// NewPostgres создает PubSub для базы по DSN
+func NewPostgres(dsn string) *Postgres {
+	return &Postgres{dsn: dsn}
+}
+
+// Publish отправляет NOTIFY в канал
+func (p *Postgres) Publish(ctx context.Context, channel string, payload []byte) error {
+	if len(payload) > MaxPostgresPayload {
+		return ErrPayloadTooLarge
+	}
+	if len(payload) == 0 {
+		return ErrEmptyPayload
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return errors.New("pubsub is closed")
+	}
+
+	if p.conn == nil || p.conn.IsClosed() {
+		conn, err := pgx.Connect(ctx, p.dsn)
+		if err != nil {
+			return fmt.Errorf("pubsub connect failed: %w", err)
+		}
+		p.conn = conn
+	}
+
+	if _, err := p.conn.Exec(ctx, "SELECT pg_notify($1, $2)", channel, string(payload)); err != nil {
+		// Соединение могло оборваться - следующая публикация переподключится
+		p.conn.Close(context.Background())
+		p.conn = nil
+		return fmt.Errorf("pubsub notify failed: %w", err)
+	}
+	return nil
+}
+
+// Subscribe выполняет LISTEN на выделенном соединении; подписка закрывается при отмене ctx или Close
+func (p *Postgres) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errors.New("pubsub is closed")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	p.cancel = append(p.cancel, cancel)
+	p.mu.Unlock()
+
+	// Первое подключение синхронно, чтобы ошибка конфигурации была видна сразу
+	conn, err := p.listen(ctx, channel)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	out := make(chan []byte, subscriberBuffer)
+	go p.receive(ctx, channel, conn, out)
+	return out, nil
+}
+
+// Close закрывает подписки и соединение для публикации
+func (p *Postgres) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	for _, cancel := range p.cancel {
+		cancel()
+	}
+	if p.conn != nil {
+		return p.conn.Close(context.Background())
+	}
+	return nil
+}
+
+func (p *Postgres) listen(ctx context.Context, channel string) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, p.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub connect failed: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		conn.Close(context.Background())
+		return nil, fmt.Errorf("pubsub listen failed: %w", err)
+	}
+	return conn, nil
+}
+
+// receive читает уведомления и переподключается при обрыве соединения
+func (p *Postgres) receive(ctx context.Context, channel string, conn *pgx.Conn, out chan<- []byte) {
+	defer close(out)
+
+	backoff := time.Second
+	for {
+		for conn != nil {
+			n, err := conn.WaitForNotification(ctx)
+			if err != nil {
+				conn.Close(context.Background())
+				conn = nil
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("PubSub connection lost on %q: %v", channel, err)
+				break
+			}
+			backoff = time.Second
+
+			select {
+			case out <- []byte(n.Payload):
+			case <-ctx.Done():
+				conn.Close(context.Background())
+				return
+			}
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, 30*time.Second)
+
+		var err error
+		if conn, err = p.listen(ctx, channel); err != nil {
+			log.Printf("PubSub reconnect on %q failed: %v", channel, err)
+			continue
+		}
+		log.Printf("PubSub reconnected on %q", channel)
+
+		select {
+		case out <- reconnected:
+		case <-ctx.Done():
+			conn.Close(context.Background())
+			return
+		}
+	}
+}
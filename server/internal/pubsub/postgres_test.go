@@ -0,0 +1,98 @@
+package pubsub
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// postgresDSN - база для проверки LISTEN/NOTIFY; без PUBSUB_TEST_DSN тесты пропускаются
+func postgresDSN(t *testing.T) string {
+	dsn := os.Getenv("PUBSUB_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PUBSUB_TEST_DSN is not set")
+	}
+	return dsn
+}
+
+func TestPostgresPublishSubscribe(t *testing.T) {
+	p := NewPostgres(postgresDSN(t))
+	defer p.Close()
+
+	ctx := context.Background()
+	ch, err := p.Subscribe(ctx, "pubsub_test")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	if err := p.Publish(ctx, "pubsub_test", []byte("hello")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got := receive(t, ch); got != "hello" {
+		t.Errorf("message = %q, want hello", got)
+	}
+}
+
+func TestPostgresReconnects(t *testing.T) {
+	dsn := postgresDSN(t)
+	p := NewPostgres(dsn)
+	defer p.Close()
+
+	ctx := context.Background()
+	ch, err := p.Subscribe(ctx, "pubsub_reconnect_test")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	// Обрываем соединение подписки со стороны сервера
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer admin.Close(ctx)
+	if _, err := admin.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		WHERE pid <> pg_backend_pid() AND query LIKE 'LISTEN%pubsub_reconnect_test%'`); err != nil {
+		t.Fatalf("terminate listener: %v", err)
+	}
+
+	// События во время переподключения теряются, поэтому публикуем, пока подписка не оживет.
+	// Перед первым сообщением после обрыва подписка отдает отметку переподключения.
+	marked := false
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := p.Publish(ctx, "pubsub_reconnect_test", []byte("back")); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				t.Fatal("subscription closed instead of reconnecting")
+			}
+			if IsReconnected(msg) {
+				marked = true
+				continue
+			}
+			if string(msg) == "back" && marked {
+				return
+			}
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	t.Fatal("subscription did not reconnect")
+}
+
+func TestPostgresSubscriptionClosesOnCancel(t *testing.T) {
+	p := NewPostgres(postgresDSN(t))
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := p.Subscribe(ctx, "pubsub_test")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	cancel()
+	waitClosed(t, ch)
+}
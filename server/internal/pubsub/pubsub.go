@@ -0,0 +1,129 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// PubSub - рассылка событий между экземплярами API (каждый подписчик получает каждое сообщение,
+// включая опубликованные им самим)
+type PubSub interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+	Close() error
+}
+
+// subscriberBuffer - размер буфера канала подписчика
+const subscriberBuffer = 256
+
+// ErrEmptyPayload - пустое сообщение зарезервировано под отметку переподключения
+var ErrEmptyPayload = errors.New("pubsub payload is empty")
+
+// reconnected - отметка в канале подписки: соединение восстановлено после обрыва
+var reconnected = []byte{}
+
+// IsReconnected - подписка переподключилась: сообщения за время обрыва потеряны, и подписчик
+// должен заново собрать состояние, которое строит по событиям. Отметка идет в общем потоке,
+// поэтому все сообщения после нее получены уже после переподключения.
+func IsReconnected(payload []byte) bool {
+	return len(payload) == 0
+}
+
+// Memory - PubSub в памяти процесса: для одного экземпляра и тестовых стендов
+type Memory struct {
+	mu     sync.RWMutex
+	subs   map[string][]chan []byte
+	closed bool
+}
+
+// NewMemory создает PubSub в памяти
+func NewMemory() *Memory {
+	return &Memory{subs: make(map[string][]chan []byte)}
+}
+
+// Publish доставляет сообщение всем подписчикам канала
+func (m *Memory) Publish(ctx context.Context, channel string, payload []byte) error {
+	if len(payload) == 0 {
+		return ErrEmptyPayload
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, ch := range m.subs[channel] {
+		select {
+		case ch <- payload:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Subscribe подписывается на канал; подписка закрывается при отмене ctx
+func (m *Memory) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	ch := make(chan []byte, subscriberBuffer)
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		close(ch)
+		return ch, nil
+	}
+	m.subs[channel] = append(m.subs[channel], ch)
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.unsubscribe(channel, ch)
+	}()
+
+	return ch, nil
+}
+
+// Interrupt имитирует обрыв и восстановление подписок канала (для тестов): подписчики получают
+// отметку IsReconnected
+func (m *Memory) Interrupt(channel string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, ch := range m.subs[channel] {
+		ch <- reconnected
+	}
+}
+
+// Close закрывает все подписки
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	for _, subs := range m.subs {
+		for _, ch := range subs {
+			close(ch)
+		}
+	}
+	m.subs = nil
+	return nil
+}
+
+func (m *Memory) unsubscribe(channel string, ch chan []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	subs := m.subs[channel]
+	for i, c := range subs {
+		if c == ch {
+			m.subs[channel] = append(subs[:i], subs[i+1:]...)
+			close(ch)
+			return
+		}
+	}
+}
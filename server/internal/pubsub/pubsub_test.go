@@ -0,0 +1,181 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// receive ждет следующее сообщение подписки
+func receive(t *testing.T, ch <-chan []byte) string {
+	t.Helper()
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return string(msg)
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+	}
+	return ""
+}
+
+// waitClosed ждет закрытия подписки
+func waitClosed(t *testing.T, ch <-chan []byte) {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("subscription was not closed")
+		}
+	}
+}
+
+func TestMemoryDeliversToEverySubscriber(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+	ctx := context.Background()
+
+	a, _ := m.Subscribe(ctx, "chat")
+	b, _ := m.Subscribe(ctx, "chat")
+	other, _ := m.Subscribe(ctx, "other")
+
+	for _, msg := range []string{"one", "two"} {
+		if err := m.Publish(ctx, "chat", []byte(msg)); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	// Каждый подписчик получает все сообщения в порядке публикации
+	for _, ch := range []<-chan []byte{a, b} {
+		if got := receive(t, ch); got != "one" {
+			t.Errorf("first message = %q, want one", got)
+		}
+		if got := receive(t, ch); got != "two" {
+			t.Errorf("second message = %q, want two", got)
+		}
+	}
+
+	select {
+	case msg := <-other:
+		t.Errorf("subscriber of another channel got %q", msg)
+	default:
+	}
+}
+
+func TestMemoryUnsubscribesOnCancel(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, _ := m.Subscribe(ctx, "chat")
+	kept, _ := m.Subscribe(context.Background(), "chat")
+
+	cancel()
+	waitClosed(t, ch)
+
+	// Оставшиеся подписчики продолжают получать сообщения
+	if err := m.Publish(context.Background(), "chat", []byte("after")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got := receive(t, kept); got != "after" {
+		t.Errorf("message = %q, want after", got)
+	}
+}
+
+func TestMemoryPublishRespectsContext(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+
+	// Подписчик не читает: после заполнения буфера публикация ждет до отмены ctx
+	m.Subscribe(context.Background(), "chat")
+	for i := 0; i < subscriberBuffer; i++ {
+		if err := m.Publish(context.Background(), "chat", []byte("x")); err != nil {
+			t.Fatalf("Publish(%d) error = %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Publish(ctx, "chat", []byte("x")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Publish() to a full subscriber error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestMemoryClose(t *testing.T) {
+	m := NewMemory()
+	ch, _ := m.Subscribe(context.Background(), "chat")
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	waitClosed(t, ch)
+
+	// Подписка после закрытия сразу закрыта, повторный Close не паникует
+	late, err := m.Subscribe(context.Background(), "chat")
+	if err != nil {
+		t.Fatalf("Subscribe() after Close error = %v", err)
+	}
+	waitClosed(t, late)
+	if err := m.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
+
+func TestMemoryInterrupt(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+
+	ctx := context.Background()
+	ch, _ := m.Subscribe(ctx, "chat")
+	other, _ := m.Subscribe(ctx, "other")
+
+	m.Publish(ctx, "chat", []byte("before"))
+	m.Interrupt("chat")
+	m.Publish(ctx, "chat", []byte("after"))
+
+	// Отметка идет в общем потоке между сообщениями
+	if got := receive(t, ch); got != "before" {
+		t.Errorf("first message = %q, want before", got)
+	}
+	if msg := <-ch; !IsReconnected(msg) {
+		t.Errorf("second message = %q, want reconnect marker", msg)
+	}
+	if got := receive(t, ch); got != "after" {
+		t.Errorf("third message = %q, want after", got)
+	}
+
+	select {
+	case msg := <-other:
+		t.Errorf("other channel received %q", msg)
+	default:
+	}
+}
+
+func TestPublishRejectsEmptyPayload(t *testing.T) {
+	// Пустое сообщение нельзя спутать с отметкой переподключения
+	for name, ps := range map[string]PubSub{
+		"memory":   NewMemory(),
+		"postgres": NewPostgres("postgres://invalid"),
+	} {
+		if err := ps.Publish(context.Background(), "chat", nil); !errors.Is(err, ErrEmptyPayload) {
+			t.Errorf("%s: Publish() error = %v, want ErrEmptyPayload", name, err)
+		}
+	}
+}
+
+func TestPostgresRejectsLargePayload(t *testing.T) {
+	// Размер проверяется до подключения к базе
+	p := NewPostgres("postgres://invalid")
+	err := p.Publish(context.Background(), "chat", make([]byte, MaxPostgresPayload+1))
+	if !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Publish() error = %v, want ErrPayloadTooLarge", err)
+	}
+}
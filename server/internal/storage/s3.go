@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"mime"
 	"path/filepath"
 	"time"
 
@@ -43,9 +44,14 @@ func NewS3Storage(accessKey, secretKey, region, bucket string) (*S3Storage, erro
 }
 
 func (s *S3Storage) UploadImage(ctx context.Context, file io.Reader, filename string, contentType string) (string, error) {
+	return s.UploadImageTo(ctx, "avatars", file, filename, contentType)
+}
+
+// UploadImageTo загружает изображение в указанный префикс бакета (avatars, thanks, ...)
+func (s *S3Storage) UploadImageTo(ctx context.Context, prefix string, file io.Reader, filename string, contentType string) (string, error) {
 	// Генерируем уникальное имя файла
 	ext := filepath.Ext(filename)
-	uniqueFilename := fmt.Sprintf("avatars/%s-%d%s", uuid.New().String(), time.Now().Unix(), ext)
+	uniqueFilename := fmt.Sprintf("%s/%s-%d%s", prefix, uuid.New().String(), time.Now().Unix(), ext)
 
 	// Загружаем в S3 (без ACL, т.к. доступ контролируется через bucket policy)
 	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
@@ -62,3 +68,53 @@ func (s *S3Storage) UploadImage(ctx context.Context, file io.Reader, filename st
 	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, uniqueFilename)
 	return url, nil
 }
+
+// UploadPrivate загружает файл в закрытый префикс бакета и возвращает ключ объекта (не URL).
+// Такие объекты недоступны по прямой ссылке - только через PresignGet.
+func (s *S3Storage) UploadPrivate(ctx context.Context, prefix string, file io.Reader, filename string, contentType string) (string, error) {
+	key := fmt.Sprintf("%s/%s%s", prefix, uuid.New().String(), filepath.Ext(filename))
+
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        file,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file to S3: %w", err)
+	}
+
+	return key, nil
+}
+
+// PresignGet возвращает временную ссылку на скачивание закрытого объекта.
+// downloadName - имя файла для сохранения (пусто - браузер покажет файл inline).
+func (s *S3Storage) PresignGet(ctx context.Context, key string, ttl time.Duration, downloadName string) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if downloadName != "" {
+		if disposition := mime.FormatMediaType("attachment", map[string]string{"filename": downloadName}); disposition != "" {
+			input.ResponseContentDisposition = aws.String(disposition)
+		}
+	}
+
+	req, err := s3.NewPresignClient(s.client).PresignGetObject(ctx, input, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign S3 object: %w", err)
+	}
+	return req.URL, nil
+}
+
+// Delete удаляет объект по ключу
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete S3 object: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package tracking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Коды поддерживаемых перевозчиков
+const (
+	RussianPost = "russian_post"
+	CDEK        = "cdek"
+	Correos     = "correos"
+	USPS        = "usps"
+	UPS         = "ups"
+	DHL         = "dhl"
+)
+
+// AllCarriers - коды всех перевозчиков, которые умеет распознавать Detect
+var AllCarriers = []string{RussianPost, CDEK, Correos, USPS, UPS, DHL}
+
+// Состояния отправления у перевозчика
+const (
+	StateUnknown   = "unknown"
+	StateInTransit = "in_transit"
+	StateDelivered = "delivered"
+	StateException = "exception" // Возврат, утеря, таможня и т.п.
+)
+
+// ErrUnsupportedCarrier - для перевозчика нет адаптера
+var ErrUnsupportedCarrier = errors.New("unsupported carrier")
+
+var (
+	// S10 (UPU): две буквы, 9 цифр, код страны - международные почтовые отправления
+	s10Re = regexp.MustCompile(`^[A-Z]{2}\d{9}([A-Z]{2})$`)
+
+	russianPostRe = regexp.MustCompile(`^\d{14}$`)
+	cdekRe        = regexp.MustCompile(`^\d{10}$`)
+	correosRe     = regexp.MustCompile(`^P[A-Z0-9]{22}$`)
+	uspsRe        = regexp.MustCompile(`^(9[1-5]\d{20}|\d{20}|\d{22})$`)
+	upsRe         = regexp.MustCompile(`^1Z[A-Z0-9]{16}$`)
+	dhlRe         = regexp.MustCompile(`^(\d{10}|JJD\d{10,20}|GM\d{16,18}|JVGL\d{8,12})$`)
+)
+
+// s10Countries - почтовые операторы по коду страны в номере S10
+var s10Countries = map[string]string{
+	"RU": RussianPost,
+	"ES": Correos,
+	"US": USPS,
+}
+
+// Normalize приводит трек-номер к каноничному виду (верхний регистр, без пробелов и дефисов)
+func Normalize(number string) string {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	return strings.NewReplacer(" ", "", "-", "").Replace(number)
+}
+
+// Detect определяет перевозчика по формату трек-номера.
+// country (ISO 3166-1 alpha-2 отправителя) разрешает неоднозначные 10-значные номера: CDEK или DHL.
+func Detect(number, country string) (string, bool) {
+	number = Normalize(number)
+	country = strings.ToUpper(country)
+
+	if m := s10Re.FindStringSubmatch(number); m != nil {
+		carrier, ok := s10Countries[m[1]]
+		return carrier, ok
+	}
+
+	switch {
+	case upsRe.MatchString(number):
+		return UPS, true
+	case correosRe.MatchString(number):
+		return Correos, true
+	case russianPostRe.MatchString(number):
+		return RussianPost, true
+	case cdekRe.MatchString(number) && (country == "RU" || country == "BY" || country == "KZ"):
+		return CDEK, true
+	case dhlRe.MatchString(number):
+		return DHL, true
+	case uspsRe.MatchString(number):
+		return USPS, true
+	}
+
+	return "", false
+}
+
+// URL возвращает публичную страницу отслеживания
+func URL(carrier, number string) string {
+	number = url.QueryEscape(Normalize(number))
+	switch carrier {
+	case RussianPost:
+		return "https://www.pochta.ru/tracking?barcode=" + number
+	case CDEK:
+		return "https://www.cdek.ru/ru/tracking?order_id=" + number
+	case Correos:
+		return "https://www.correos.es/es/es/herramientas/localizador/envios/detalle?tracking-number=" + number
+	case USPS:
+		return "https://tools.usps.com/go/TrackConfirmAction?tLabels=" + number
+	case UPS:
+		return "https://www.ups.com/track?tracknum=" + number
+	case DHL:
+		return "https://www.dhl.com/global-en/home/tracking/tracking-express.html?tracking-id=" + number
+	}
+	return ""
+}
+
+// Status - состояние отправления по данным перевозчика
+type Status struct {
+	State       string
+	Description string
+	UpdatedAt   time.Time
+}
+
+// Carrier - адаптер API перевозчика
+type Carrier interface {
+	Code() string
+	Track(ctx context.Context, number string) (Status, error)
+}
+
+// Registry - набор адаптеров по коду перевозчика
+type Registry struct {
+	carriers map[string]Carrier
+}
+
+// NewRegistry создает реестр из адаптеров
+func NewRegistry(carriers ...Carrier) *Registry {
+	r := &Registry{carriers: make(map[string]Carrier, len(carriers))}
+	for _, c := range carriers {
+		r.carriers[c.Code()] = c
+	}
+	return r
+}
+
+// Track запрашивает статус у адаптера нужного перевозчика
+func (r *Registry) Track(ctx context.Context, carrier, number string) (Status, error) {
+	c, ok := r.carriers[carrier]
+	if !ok {
+		return Status{}, ErrUnsupportedCarrier
+	}
+	return c.Track(ctx, Normalize(number))
+}
+
+// Len возвращает количество зарегистрированных адаптеров
+func (r *Registry) Len() int {
+	return len(r.carriers)
+}
+
+// HTTPCarrier - адаптер к агрегатору трекинга с JSON API:
+// GET {baseURL}/{carrier}/{number} -> {"state": "...", "description": "...", "updated_at": "RFC3339"}
+type HTTPCarrier struct {
+	code    string
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewHTTPCarrier создает адаптер для перевозчика code
+func NewHTTPCarrier(code, baseURL, apiKey string) *HTTPCarrier {
+	return &HTTPCarrier{
+		code:    code,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Code возвращает код перевозчика
+func (c *HTTPCarrier) Code() string {
+	return c.code
+}
+
+// Track запрашивает статус отправления
+func (c *HTTPCarrier) Track(ctx context.Context, number string) (Status, error) {
+	endpoint := fmt.Sprintf("%s/%s/%s", c.baseURL, url.PathEscape(c.code), url.PathEscape(number))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Status{}, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Status{}, fmt.Errorf("tracking request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Status{State: StateUnknown}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Status{}, fmt.Errorf("tracking API returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		State       string    `json:"state"`
+		Description string    `json:"description"`
+		UpdatedAt   time.Time `json:"updated_at"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body); err != nil {
+		return Status{}, fmt.Errorf("failed to parse tracking response: %w", err)
+	}
+
+	switch body.State {
+	case StateInTransit, StateDelivered, StateException:
+	default:
+		body.State = StateUnknown
+	}
+
+	return Status{
+		State:       body.State,
+		Description: body.Description,
+		UpdatedAt:   body.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,196 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize("  ra 1234-56789 ru "); got != "RA123456789RU" {
+		t.Errorf("Normalize() = %q, want RA123456789RU", got)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		number  string
+		country string
+		want    string
+		ok      bool
+	}{
+		// S10: оператор по коду страны
+		{"RA123456789RU", "", RussianPost, true},
+		{"ra 123 456 789 ru", "", RussianPost, true},
+		{"EE123456789ES", "", Correos, true},
+		{"LZ123456789US", "", USPS, true},
+		{"RA123456789CN", "", "", false}, // Оператор без адаптера
+
+		{"80082547123456", "", RussianPost, true}, // 14 цифр
+		{"PQ4B3C0123456780128001A", "", Correos, true},
+		{"1Z999AA10123456784", "", UPS, true},
+		{"9400111899223817000000", "", USPS, true},
+		{"12345678901234567890", "", USPS, true},
+		{"JJD0123456789012", "", DHL, true},
+		{"GM1234567890123456", "", DHL, true},
+
+		// 10 цифр: CDEK для СНГ, иначе DHL Express
+		{"1234567890", "RU", CDEK, true},
+		{"1234567890", "kz", CDEK, true},
+		{"1234567890", "DE", DHL, true},
+		{"1234567890", "", DHL, true},
+
+		{"", "", "", false},
+		{"hello", "", "", false},
+		{"123", "RU", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Detect(tt.number, tt.country)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Detect(%q, %q) = (%q, %v), want (%q, %v)", tt.number, tt.country, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	for _, carrier := range AllCarriers {
+		if URL(carrier, "x") == "" {
+			t.Errorf("URL(%s) is empty", carrier)
+		}
+	}
+	if URL("unknown", "x") != "" {
+		t.Error("URL(unknown) is not empty")
+	}
+}
+
+// stand - локальная замена агрегатора трекинга: ответ по трек-номеру
+func stand(t *testing.T, handler http.HandlerFunc) *HTTPCarrier {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewHTTPCarrier(CDEK, srv.URL+"/", "secret")
+}
+
+func TestHTTPCarrierStatusMapping(t *testing.T) {
+	updatedAt := time.Date(2025, 12, 20, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		state string
+		want  string
+	}{
+		{"in_transit", StateInTransit},
+		{"delivered", StateDelivered},
+		{"exception", StateException},
+		{"out_for_delivery", StateUnknown}, // Неизвестные состояния не пропускаем дальше
+		{"", StateUnknown},
+	}
+
+	for _, tt := range tests {
+		carrier := stand(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/cdek/1234567890" {
+				t.Errorf("path = %s, want /cdek/1234567890", r.URL.Path)
+			}
+			if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+				t.Errorf("Authorization = %q", got)
+			}
+			fmt.Fprintf(w, `{"state": %q, "description": "Sorting center", "updated_at": %q}`,
+				tt.state, updatedAt.Format(time.RFC3339))
+		})
+
+		status, err := carrier.Track(context.Background(), "1234567890")
+		if err != nil {
+			t.Fatalf("Track(%q) error = %v", tt.state, err)
+		}
+		if status.State != tt.want {
+			t.Errorf("Track(%q).State = %q, want %q", tt.state, status.State, tt.want)
+		}
+		if status.Description != "Sorting center" || !status.UpdatedAt.Equal(updatedAt) {
+			t.Errorf("Track(%q) = %+v", tt.state, status)
+		}
+	}
+}
+
+func TestHTTPCarrierNotFound(t *testing.T) {
+	carrier := stand(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	status, err := carrier.Track(context.Background(), "1234567890")
+	if err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	if status.State != StateUnknown {
+		t.Errorf("State = %q, want %q", status.State, StateUnknown)
+	}
+}
+
+func TestHTTPCarrierErrors(t *testing.T) {
+	tests := map[string]http.HandlerFunc{
+		"server error": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		},
+		"unauthorized": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		},
+		"invalid json": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<html>maintenance</html>"))
+		},
+	}
+
+	for name, handler := range tests {
+		if _, err := stand(t, handler).Track(context.Background(), "1234567890"); err == nil {
+			t.Errorf("%s: Track() succeeded, want error", name)
+		}
+	}
+}
+
+func TestHTTPCarrierTimeout(t *testing.T) {
+	release := make(chan struct{})
+	carrier := stand(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := carrier.Track(ctx, "1234567890")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Track() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Track() returned after %s, want it to honor the context deadline", elapsed)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	carrier := stand(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cdek/1234567890" {
+			t.Errorf("path = %s: number must be normalized", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"state": "in_transit"}`)
+	})
+	registry := NewRegistry(carrier)
+
+	if registry.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", registry.Len())
+	}
+
+	status, err := registry.Track(context.Background(), CDEK, " 12345-67890 ")
+	if err != nil || status.State != StateInTransit {
+		t.Errorf("Track(cdek) = %+v, %v", status, err)
+	}
+
+	if _, err := registry.Track(context.Background(), DHL, "1234567890"); !errors.Is(err, ErrUnsupportedCarrier) {
+		t.Errorf("Track(dhl) error = %v, want ErrUnsupportedCarrier", err)
+	}
+}
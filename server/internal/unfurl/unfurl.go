@@ -0,0 +1,401 @@
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Ограничения по умолчанию для загрузки страниц
+const (
+	DefaultTimeout      = 5 * time.Second
+	DefaultMaxBodyBytes = 1 << 20 // 1 MB: метаданные всегда в начале страницы
+	DefaultMaxRedirects = 3
+
+	maxFieldLength = 1000
+)
+
+// ErrPrivateAddress - адрес назначения во внутренней сети (защита от SSRF)
+var ErrPrivateAddress = errors.New("destination address is not allowed")
+
+// Preview - метаданные страницы товара
+type Preview struct {
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+	Price       string // Десятичная строка, как на странице ("1299.00")
+	Currency    string // ISO 4217
+}
+
+// Fetcher загружает метаданные страницы по ссылке
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*Preview, error)
+}
+
+// HTTPFetcher - загрузчик Open Graph / JSON-LD метаданных по HTTP
+type HTTPFetcher struct {
+	client       *http.Client
+	maxBodyBytes int64
+}
+
+// Options - настройки HTTPFetcher
+type Options struct {
+	Timeout      time.Duration
+	MaxBodyBytes int64
+	MaxRedirects int
+
+	// AllowPrivateNetworks разрешает внутренние адреса (только для локального стенда)
+	AllowPrivateNetworks bool
+}
+
+// NewHTTPFetcher создает загрузчик с защитой от SSRF
+func NewHTTPFetcher(opts Options) *HTTPFetcher {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxBodyBytes == 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.MaxRedirects == 0 {
+		opts.MaxRedirects = DefaultMaxRedirects
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivateNetworks {
+		// Проверяем уже разрешенный IP при каждом соединении (включая редиректы),
+		// поэтому подмена DNS между проверкой и подключением не поможет
+		dialer.Control = publicOnlyControl
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil, // Прокси обошел бы проверку адреса
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// via содержит исходный запрос и все пройденные редиректы
+			if len(via) > opts.MaxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return errors.New("unsupported redirect scheme")
+			}
+			return nil
+		},
+	}
+
+	return &HTTPFetcher{client: client, maxBodyBytes: opts.MaxBodyBytes}
+}
+
+// Fetch загружает страницу и извлекает метаданные
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.New("invalid url")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "SecretSantaBot/1.0 (+link preview)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" && !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("unsupported content type: %s", contentType)
+	}
+
+	preview := Parse(io.LimitReader(resp.Body, f.maxBodyBytes))
+	preview.ImageURL = resolveURL(resp.Request.URL, preview.ImageURL)
+	return preview, nil
+}
+
+// Parse извлекает Open Graph и JSON-LD (schema.org Product) метаданные из HTML
+func Parse(r io.Reader) *Preview {
+	p := &Preview{}
+	var pageTitle string
+	var jsonLD []string
+
+	z := html.NewTokenizer(r)
+	inTitle, inJSONLD := false, false
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			// Конец документа или обрезка по лимиту размера
+			for _, block := range jsonLD {
+				applyJSONLD(p, block)
+			}
+			if p.Title == "" {
+				p.Title = pageTitle
+			}
+			p.Title = truncate(p.Title)
+			p.Description = truncate(p.Description)
+			p.SiteName = truncate(p.SiteName)
+			return p
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "meta":
+				applyMeta(p, tok.Attr)
+			case "title":
+				inTitle = true
+			case "script":
+				inJSONLD = strings.EqualFold(attr(tok.Attr, "type"), "application/ld+json")
+			}
+
+		case html.TextToken:
+			if inTitle && pageTitle == "" {
+				pageTitle = strings.TrimSpace(string(z.Text()))
+			}
+			if inJSONLD {
+				jsonLD = append(jsonLD, string(z.Text()))
+			}
+
+		case html.EndTagToken:
+			inTitle, inJSONLD = false, false
+		}
+	}
+}
+
+func applyMeta(p *Preview, attrs []html.Attribute) {
+	key := attr(attrs, "property")
+	if key == "" {
+		key = attr(attrs, "name")
+	}
+	value := strings.TrimSpace(attr(attrs, "content"))
+	if value == "" {
+		return
+	}
+
+	set := func(field *string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+
+	switch strings.ToLower(key) {
+	case "og:title", "twitter:title":
+		set(&p.Title)
+	case "og:description", "twitter:description", "description":
+		set(&p.Description)
+	case "og:image", "og:image:secure_url", "twitter:image":
+		set(&p.ImageURL)
+	case "og:site_name":
+		set(&p.SiteName)
+	case "product:price:amount", "og:price:amount":
+		set(&p.Price)
+	case "product:price:currency", "og:price:currency":
+		value = strings.ToUpper(value)
+		set(&p.Currency)
+	}
+}
+
+// applyJSONLD дополняет превью данными schema.org Product
+func applyJSONLD(p *Preview, block string) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(block), &data); err != nil {
+		return
+	}
+
+	product := findProduct(data)
+	if product == nil {
+		return
+	}
+
+	if p.Title == "" {
+		p.Title = stringValue(product["name"])
+	}
+	if p.Description == "" {
+		p.Description = stringValue(product["description"])
+	}
+	if p.ImageURL == "" {
+		p.ImageURL = stringValue(product["image"])
+	}
+
+	offer := product["offers"]
+	if list, ok := offer.([]interface{}); ok && len(list) > 0 {
+		offer = list[0]
+	}
+	if o, ok := offer.(map[string]interface{}); ok {
+		if p.Price == "" {
+			p.Price = stringValue(o["price"])
+			if p.Price == "" {
+				p.Price = stringValue(o["lowPrice"])
+			}
+		}
+		if p.Currency == "" {
+			p.Currency = strings.ToUpper(stringValue(o["priceCurrency"]))
+		}
+	}
+}
+
+// findProduct ищет объект с @type Product (в т.ч. внутри массивов и @graph)
+func findProduct(data interface{}) map[string]interface{} {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if product := findProduct(item); product != nil {
+				return product
+			}
+		}
+	case map[string]interface{}:
+		if isProductType(v["@type"]) {
+			return v
+		}
+		if graph, ok := v["@graph"]; ok {
+			return findProduct(graph)
+		}
+	}
+	return nil
+}
+
+func isProductType(t interface{}) bool {
+	switch v := t.(type) {
+	case string:
+		return v == "Product"
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == "Product" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stringValue приводит значение JSON к строке (image может быть массивом или объектом)
+func stringValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return strings.TrimSpace(val)
+	case float64:
+		return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", val), "0"), ".")
+	case []interface{}:
+		if len(val) > 0 {
+			return stringValue(val[0])
+		}
+	case map[string]interface{}:
+		return stringValue(val["url"])
+	}
+	return ""
+}
+
+func attr(attrs []html.Attribute, name string) string {
+	for _, a := range attrs {
+		if strings.EqualFold(a.Key, name) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// resolveURL делает ссылку на изображение абсолютной и отбрасывает не-http схемы
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+func truncate(s string) string {
+	s = strings.TrimSpace(s)
+	runes := []rune(s)
+	if len(runes) > maxFieldLength {
+		return string(runes[:maxFieldLength])
+	}
+	return s
+}
+
+// privateNetworks - диапазоны, не входящие в IsPrivate/IsLoopback стандартной библиотеки
+var privateNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "this" network
+	"100.64.0.0/10", // Carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // Benchmarking
+	"64:ff9b::/96",  // NAT64 (может указывать на внутренний IPv4)
+)
+
+// publicOnlyControl - net.Dialer.Control, запрещающий соединения с непубличными адресами
+func publicOnlyControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// isPublicIP проверяет, что адрес маршрутизируется в публичном интернете
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
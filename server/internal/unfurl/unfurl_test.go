@@ -0,0 +1,304 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want Preview
+	}{
+		{
+			name: "open graph",
+			html: `<html><head>
+				<title>Page title</title>
+				<meta property="og:title" content="Mug">
+				<meta property="og:description" content="Big red mug">
+				<meta property="og:image" content="/img/mug.jpg">
+				<meta property="og:site_name" content="Shop">
+				<meta property="product:price:amount" content="12.50">
+				<meta property="product:price:currency" content="eur">
+			</head></html>`,
+			want: Preview{Title: "Mug", Description: "Big red mug", ImageURL: "/img/mug.jpg", SiteName: "Shop", Price: "12.50", Currency: "EUR"},
+		},
+		{
+			name: "twitter card",
+			html: `<head>
+				<meta name="twitter:title" content="Scarf">
+				<meta name="twitter:description" content="Wool scarf">
+				<meta name="twitter:image" content="https://cdn.example.com/scarf.png">
+			</head>`,
+			want: Preview{Title: "Scarf", Description: "Wool scarf", ImageURL: "https://cdn.example.com/scarf.png"},
+		},
+		{
+			name: "open graph wins over twitter",
+			html: `<meta property="og:title" content="OG"><meta name="twitter:title" content="Twitter">`,
+			want: Preview{Title: "OG"},
+		},
+		{
+			name: "title fallback",
+			html: `<html><head><title>  Plain page  </title><meta name="description" content="About"></head></html>`,
+			want: Preview{Title: "Plain page", Description: "About"},
+		},
+		{
+			name: "json-ld product",
+			html: `<script type="application/ld+json">
+				{"@graph": [{"@type": "Product", "name": "Socks", "image": ["/s.jpg"],
+				 "offers": {"price": 9.9, "priceCurrency": "usd"}}]}
+			</script>`,
+			want: Preview{Title: "Socks", ImageURL: "/s.jpg", Price: "9.9", Currency: "USD"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(strings.NewReader(tt.html))
+			if *got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTruncatesFields(t *testing.T) {
+	long := strings.Repeat("я", maxFieldLength+50)
+	got := Parse(strings.NewReader(`<meta property="og:title" content="` + long + `">`))
+	if n := len([]rune(got.Title)); n != maxFieldLength {
+		t.Errorf("title length = %d, want %d", n, maxFieldLength)
+	}
+}
+
+// localFetcher - загрузчик для httptest.Server (он слушает loopback)
+func localFetcher(opts Options) *HTTPFetcher {
+	opts.AllowPrivateNetworks = true
+	return NewHTTPFetcher(opts)
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<meta property="og:title" content="Mug"><meta property="og:image" content="/img/mug.jpg">`)
+	}))
+	defer srv.Close()
+
+	got, err := localFetcher(Options{}).Fetch(context.Background(), srv.URL+"/item/1")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if got.Title != "Mug" {
+		t.Errorf("Title = %q, want Mug", got.Title)
+	}
+	if want := srv.URL + "/img/mug.jpg"; got.ImageURL != want {
+		t.Errorf("ImageURL = %q, want %q (resolved against page URL)", got.ImageURL, want)
+	}
+}
+
+func TestFetchRejectsNonHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	}))
+	defer srv.Close()
+
+	if _, err := localFetcher(Options{}).Fetch(context.Background(), srv.URL); err == nil {
+		t.Error("Fetch() of an image succeeded, want error")
+	}
+}
+
+func TestFetchNonOK(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	if _, err := localFetcher(Options{}).Fetch(context.Background(), srv.URL); err == nil {
+		t.Error("Fetch() of 404 succeeded, want error")
+	}
+}
+
+func TestFetchSizeCap(t *testing.T) {
+	const limit = 4096
+	padding := strings.Repeat("<p>filler</p>", 2*limit/len("<p>filler</p>"))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/early" {
+			fmt.Fprint(w, `<meta property="og:title" content="Early">`+padding)
+			return
+		}
+		// Метаданные за пределами лимита не читаются
+		fmt.Fprint(w, padding+`<meta property="og:title" content="Late">`)
+	}))
+	defer srv.Close()
+
+	f := localFetcher(Options{MaxBodyBytes: limit})
+
+	got, err := f.Fetch(context.Background(), srv.URL+"/early")
+	if err != nil {
+		t.Fatalf("Fetch(early) error = %v", err)
+	}
+	if got.Title != "Early" {
+		t.Errorf("Fetch(early).Title = %q, want Early", got.Title)
+	}
+
+	got, err = f.Fetch(context.Background(), srv.URL+"/late")
+	if err != nil {
+		t.Fatalf("Fetch(late) error = %v", err)
+	}
+	if got.Title != "" {
+		t.Errorf("Fetch(late).Title = %q, want empty: body past the cap must not be read", got.Title)
+	}
+}
+
+func TestFetchRedirectLimit(t *testing.T) {
+	// /hop/N перенаправляет на /hop/N-1, /hop/0 отдает страницу
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if n > 0 {
+			http.Redirect(w, r, "/hop/"+strconv.Itoa(n-1), http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<title>Final</title>`)
+	}))
+	defer srv.Close()
+
+	f := localFetcher(Options{MaxRedirects: 2})
+
+	got, err := f.Fetch(context.Background(), srv.URL+"/hop/2")
+	if err != nil {
+		t.Fatalf("Fetch() with 2 redirects error = %v", err)
+	}
+	if got.Title != "Final" {
+		t.Errorf("Title = %q, want Final", got.Title)
+	}
+
+	if _, err := f.Fetch(context.Background(), srv.URL+"/hop/3"); err == nil || !strings.Contains(err.Error(), "too many redirects") {
+		t.Errorf("Fetch() with 3 redirects error = %v, want too many redirects", err)
+	}
+}
+
+func TestFetchRejectsRedirectToOtherScheme(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	if _, err := localFetcher(Options{}).Fetch(context.Background(), srv.URL); err == nil {
+		t.Error("Fetch() followed a redirect to ftp://, want error")
+	}
+}
+
+func TestFetchInvalidURL(t *testing.T) {
+	f := NewHTTPFetcher(Options{})
+	for _, raw := range []string{"", "not a url", "file:///etc/passwd", "javascript:alert(1)", "http://"} {
+		if _, err := f.Fetch(context.Background(), raw); err == nil {
+			t.Errorf("Fetch(%q) succeeded, want error", raw)
+		}
+	}
+}
+
+func TestFetchBlocksLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer srv.Close()
+
+	_, err := NewHTTPFetcher(Options{}).Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Fetch() error = %v, want ErrPrivateAddress", err)
+	}
+}
+
+func TestFetchBlocksRedirectToLoopback(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect reached a loopback server")
+	}))
+	defer internal.Close()
+
+	// Публичных адресов в тесте нет, поэтому проверяем сам Control на адресе из редиректа:
+	// он вызывается при каждом соединении, в том числе после редиректа
+	host := strings.TrimPrefix(internal.URL, "http://")
+	if err := publicOnlyControl("tcp", host, nil); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("publicOnlyControl(%s) = %v, want ErrPrivateAddress", host, err)
+	}
+}
+
+func TestPublicOnlyControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"127.0.0.1:80", false},
+		{"[::1]:443", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false}, // Метаданные облака
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"100.64.0.1:80", false},
+		{"[::ffff:127.0.0.1]:80", false}, // IPv4-mapped loopback
+		{"93.184.216.34:443", true},
+		{"[2606:4700::1111]:443", true},
+		{"example.com:80", false}, // Control получает только IP - имя означает ошибку резолвинга
+	}
+
+	for _, tt := range tests {
+		err := publicOnlyControl("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("publicOnlyControl(%s) = %v, want allowed", tt.address, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("publicOnlyControl(%s) = %v, want ErrPrivateAddress", tt.address, err)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"127.255.255.254", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.31.255.255", false},
+		{"192.168.0.1", false},
+		{"169.254.1.1", false},
+		{"fe80::abcd", false},
+		{"fc00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.100.100.100", false}, // CGNAT
+		{"198.18.0.1", false},
+		{"192.0.0.8", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"64:ff9b::a00:1", false}, // NAT64 на 10.0.0.1
+		{"::ffff:10.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if ip == nil {
+			t.Fatalf("bad test IP %q", tt.ip)
+		}
+		if got := isPublicIP(ip); got != tt.public {
+			t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.public)
+		}
+	}
+}
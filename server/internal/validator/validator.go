@@ -2,8 +2,10 @@ package validator
 
 import (
 	"errors"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -19,6 +21,13 @@ const (
 	MaxCountryCodeLength = 2
 	MaxWishlistLength    = 10000
 	MaxAboutLength       = 10000
+	MaxTimezoneLength    = 64
+	MaxURLLength         = 2000
+	MaxItemTitleLength   = 200
+	MaxItemNotesLength   = 2000
+	MaxCarrierLength     = 50
+	MaxTrackingLength    = 40
+	MaxThankYouLength    = 2000
 )
 
 // Опасные паттерны для SQL/NoSQL injection
@@ -145,6 +154,75 @@ func ValidatePostalCode(code string) error {
 	return nil
 }
 
+// ValidateTimezone проверяет часовой пояс IANA ("Europe/Moscow")
+func ValidateTimezone(tz string) error {
+	if tz == "" {
+		return nil
+	}
+
+	tz = SanitizeString(tz)
+
+	if len(tz) > MaxTimezoneLength {
+		return errors.New("timezone is too long")
+	}
+
+	// time.LoadLocation принимает и "Local", который зависит от сервера
+	if tz == "Local" {
+		return errors.New("invalid timezone")
+	}
+
+	if _, err := time.LoadLocation(tz); err != nil {
+		return errors.New("invalid timezone")
+	}
+
+	return nil
+}
+
+// ValidateURL проверяет ссылку (только http/https с хостом)
+func ValidateURL(rawURL string) error {
+	if rawURL == "" {
+		return nil
+	}
+
+	rawURL = SanitizeString(rawURL)
+
+	if len(rawURL) > MaxURLLength {
+		return errors.New("url is too long")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return errors.New("invalid url")
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("url must use http or https")
+	}
+
+	return nil
+}
+
+// ValidateTrackingNumber проверяет трек-номер отправления
+func ValidateTrackingNumber(number string) error {
+	if number == "" {
+		return nil
+	}
+
+	number = SanitizeString(number)
+
+	if len(number) > MaxTrackingLength {
+		return errors.New("tracking number is too long")
+	}
+
+	// Только латинские буквы, цифры, пробелы и дефисы
+	trackingRegex := regexp.MustCompile(`^[A-Za-z0-9\s\-]+$`)
+	if !trackingRegex.MatchString(number) {
+		return errors.New("tracking number contains invalid characters")
+	}
+
+	return nil
+}
+
 // ValidateProfileData проверяет все данные профиля
 func ValidateProfileData(data map[string]string) error {
 	// Проверяем телефон
@@ -162,6 +240,11 @@ func ValidateProfileData(data map[string]string) error {
 		return err
 	}
 
+	// Проверяем часовой пояс
+	if err := ValidateTimezone(data["timezone"]); err != nil {
+		return err
+	}
+
 	// Проверяем текстовые поля
 	fields := map[string]int{
 		"about":            MaxAboutLength,